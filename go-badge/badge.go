@@ -2,6 +2,7 @@ package badge
 
 import (
 	"bytes"
+	"fmt"
 	"html/template"
 	"io"
 	"strings"
@@ -16,7 +17,9 @@ type badge struct {
 	Subject string
 	Status  string
 	Color   Color
-	Bounds  bounds
+	// Logo is an optional image URL (usually a data URI) drawn in front of the subject.
+	Logo   template.URL
+	Bounds bounds
 }
 
 type bounds struct {
@@ -39,22 +42,7 @@ type badgeDrawer struct {
 }
 
 func (d *badgeDrawer) StringRender(subject, status string, color Color, w io.Writer) string {
-	d.mutex.Lock()
-	subjectDx := d.measureString(subject)
-	statusDx := d.measureString(status)
-	d.mutex.Unlock()
-
-	bdg := badge{
-		Subject: subject,
-		Status:  status,
-		Color:   color,
-		Bounds: bounds{
-			SubjectDx: subjectDx,
-			SubjectX:  subjectDx/2.0 + 1,
-			StatusDx:  statusDx,
-			StatusX:   subjectDx + statusDx/2.0 - 1,
-		},
-	}
+	bdg := d.newBadge(subject, status, color, "")
 	var doc bytes.Buffer
 	err := d.tmpl.Execute(&doc, bdg)
 	s := strings.TrimSpace(doc.String())
@@ -65,23 +53,55 @@ func (d *badgeDrawer) StringRender(subject, status string, color Color, w io.Wri
 }
 
 func (d *badgeDrawer) Render(subject, status string, color Color, w io.Writer) error {
+	return d.tmpl.Execute(w, d.newBadge(subject, status, color, ""))
+}
+
+func (d *badgeDrawer) RenderWithLogo(subject, status string, color Color, logo string, w io.Writer) error {
+	url, err := logoTemplateURL(logo)
+	if err != nil {
+		return err
+	}
+	return d.tmpl.Execute(w, d.newBadge(subject, status, color, url))
+}
+
+// logoTemplateURL marks logo as safe to put in the SVG, which html/template would
+// otherwise refuse for data URIs. As that also turns off its check of the URL scheme,
+// only image data URIs and http(s) URLs are allowed, so that e.g. a javascript: URL
+// can't end up in a badge served inline.
+func logoTemplateURL(logo string) (template.URL, error) {
+	lower := strings.ToLower(strings.TrimSpace(logo))
+	if logo == "" || strings.HasPrefix(lower, "data:image/") ||
+		strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://") {
+		return template.URL(logo), nil
+	}
+	return "", fmt.Errorf("badge: logo must be an image data URI or an http(s) URL, not %q", logo)
+}
+
+// logoDx is the room taken by a logo in front of the subject: 14px image plus padding.
+const logoDx = 17
+
+func (d *badgeDrawer) newBadge(subject, status string, color Color, logo template.URL) badge {
 	d.mutex.Lock()
 	subjectDx := d.measureString(subject)
 	statusDx := d.measureString(status)
 	d.mutex.Unlock()
 
-	bdg := badge{
+	var offset float64
+	if logo != "" {
+		offset = logoDx
+	}
+	return badge{
 		Subject: subject,
 		Status:  status,
 		Color:   color,
+		Logo:    logo,
 		Bounds: bounds{
-			SubjectDx: subjectDx,
-			SubjectX:  subjectDx/2.0 + 1,
+			SubjectDx: offset + subjectDx,
+			SubjectX:  offset + subjectDx/2.0 + 1,
 			StatusDx:  statusDx,
-			StatusX:   subjectDx + statusDx/2.0 - 1,
+			StatusX:   offset + subjectDx + statusDx/2.0 - 1,
 		},
 	}
-	return d.tmpl.Execute(w, bdg)
 }
 
 // shield.io uses Verdana.ttf to measure text width with an extra 10px.
@@ -102,6 +122,12 @@ func StringRender(subject, status string, color Color, w io.Writer) string {
 	return drawer.StringRender(subject, status, color, w)
 }
 
+// RenderWithLogo renders a badge like Render, with the image at logo (an http(s) URL or a
+// data:image/ URI) drawn in front of the subject. Other URLs are rejected with an error.
+func RenderWithLogo(subject, status string, color Color, logo string, w io.Writer) error {
+	return drawer.RenderWithLogo(subject, status, color, logo, w)
+}
+
 const (
 	dpi      = 72
 	fontsize = 11
@@ -0,0 +1,38 @@
+package badge
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRenderWithLogo(t *testing.T) {
+	for _, logo := range []string{
+		"data:image/png;base64,iVBORw0KGgo=",
+		"https://example.com/logo.svg",
+		"HTTP://example.com/logo.svg",
+	} {
+		var buf bytes.Buffer
+		if err := RenderWithLogo("build", "passing", ColorGreen, logo, &buf); err != nil {
+			t.Errorf("%s: %s", logo, err)
+		} else if !strings.Contains(buf.String(), `xlink:href="`+logo+`"`) {
+			t.Errorf("%s: expected the logo in the badge, got %s", logo, buf.String())
+		}
+	}
+
+	for _, logo := range []string{
+		"javascript:alert(1)",
+		" JavaScript:alert(1)",
+		"data:text/html;base64,PHNjcmlwdD4=",
+		"file:///etc/passwd",
+		"logo.svg",
+	} {
+		var buf bytes.Buffer
+		if err := RenderWithLogo("build", "passing", ColorGreen, logo, &buf); err == nil {
+			t.Errorf("%s: expected an error", logo)
+		}
+		if buf.Len() != 0 {
+			t.Errorf("%s: expected nothing to be written, got %s", logo, buf.String())
+		}
+	}
+}
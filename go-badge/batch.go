@@ -0,0 +1,182 @@
+package badge
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Spec describes a set of badges that are rendered together, e.g. all the static
+// badges of a README. It can be read from JSON or YAML:
+//
+//	badges:
+//	  - subject: license
+//	    status: MIT
+//	    color: blue
+//	    logo: logos/scale.svg
+//	    output: badges/license.svg
+type Spec struct {
+	Badges []BadgeSpec `json:"badges" yaml:"badges"`
+}
+
+// BadgeSpec describes a single badge of a Spec.
+type BadgeSpec struct {
+	Subject string `json:"subject" yaml:"subject"`
+	Status  string `json:"status" yaml:"status"`
+	// Color is either a name from ColorScheme or a hex color.
+	Color string `json:"color" yaml:"color"`
+	// Style must be empty or "flat", the only style currently supported.
+	Style string `json:"style" yaml:"style"`
+	// Logo is a data URI, an http(s) URL, or the path of an image file which is
+	// embedded into the badge. Relative paths are resolved against the spec directory.
+	Logo string `json:"logo" yaml:"logo"`
+	// Output is the path of the SVG file to write. Relative paths are resolved
+	// against the spec directory.
+	Output string `json:"output" yaml:"output"`
+}
+
+// BatchResult reports the outcome of rendering a Spec. Both lists hold output paths.
+type BatchResult struct {
+	// Updated lists the files that were created or whose content changed.
+	Updated []string
+	// Unchanged lists the files that already had the rendered content.
+	Unchanged []string
+}
+
+// BatchError collects the errors of all badges that failed to render.
+type BatchError []error
+
+func (e BatchError) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// ParseSpec decodes a Spec from data. format is "json" or "yaml".
+func ParseSpec(data []byte, format string) (*Spec, error) {
+	spec := &Spec{}
+	var err error
+	switch strings.ToLower(format) {
+	case "json":
+		err = json.Unmarshal(data, spec)
+	case "yaml", "yml":
+		err = yaml.Unmarshal(data, spec)
+	default:
+		return nil, fmt.Errorf("badge: unknown spec format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
+
+// ReadSpecFile reads a Spec from path. The format is chosen from the file extension.
+func ReadSpecFile(path string) (*Spec, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseSpec(data, strings.TrimPrefix(filepath.Ext(path), "."))
+}
+
+// RenderSpecFile reads the Spec at path and renders all its badges, resolving relative
+// paths against the directory of the spec file.
+func RenderSpecFile(path string) (BatchResult, error) {
+	spec, err := ReadSpecFile(path)
+	if err != nil {
+		return BatchResult{}, err
+	}
+	return spec.Render(filepath.Dir(path))
+}
+
+// Render renders every badge of the spec in one pass. Relative logo and output paths
+// are resolved against baseDir. Files are only rewritten when their content changes.
+// Badges that fail don't stop the others; their errors are returned as a BatchError.
+func (s *Spec) Render(baseDir string) (BatchResult, error) {
+	var result BatchResult
+	var errs BatchError
+	for i, b := range s.Badges {
+		out := b.Output
+		if out == "" {
+			errs = append(errs, fmt.Errorf("badge %d (%s): no output path", i, b.Subject))
+			continue
+		}
+		if !filepath.IsAbs(out) {
+			out = filepath.Join(baseDir, out)
+		}
+		updated, err := b.renderTo(out, baseDir)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("badge %d (%s): %s", i, b.Subject, err))
+			continue
+		}
+		if updated {
+			result.Updated = append(result.Updated, out)
+		} else {
+			result.Unchanged = append(result.Unchanged, out)
+		}
+	}
+	if len(errs) > 0 {
+		return result, errs
+	}
+	return result, nil
+}
+
+// renderTo renders the badge and writes it to path if the content differs from what's
+// already there. It reports whether the file was written.
+func (b BadgeSpec) renderTo(path string, baseDir string) (bool, error) {
+	if b.Style != "" && b.Style != "flat" {
+		return false, fmt.Errorf("unsupported style %q", b.Style)
+	}
+	logo, err := logoURL(b.Logo, baseDir)
+	if err != nil {
+		return false, err
+	}
+
+	var doc bytes.Buffer
+	if err := drawer.RenderWithLogo(b.Subject, b.Status, Color(b.Color), logo, &doc); err != nil {
+		return false, err
+	}
+
+	old, err := ioutil.ReadFile(path)
+	if err == nil && bytes.Equal(old, doc.Bytes()) {
+		return false, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return false, err
+	}
+	if err := ioutil.WriteFile(path, doc.Bytes(), 0644); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// logoURL turns the logo of a BadgeSpec into something that can be referenced from the
+// SVG. URLs are used as-is, files are embedded as a data URI.
+func logoURL(logo string, baseDir string) (string, error) {
+	if logo == "" || strings.HasPrefix(logo, "data:") ||
+		strings.HasPrefix(logo, "http://") || strings.HasPrefix(logo, "https://") {
+		return logo, nil
+	}
+	if !filepath.IsAbs(logo) {
+		logo = filepath.Join(baseDir, logo)
+	}
+	data, err := ioutil.ReadFile(logo)
+	if err != nil {
+		return "", err
+	}
+	mimeType := mime.TypeByExtension(filepath.Ext(logo))
+	if mimeType == "" {
+		mimeType = "image/svg+xml"
+	}
+	return "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data), nil
+}
@@ -0,0 +1,116 @@
+package badge
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSpecRender(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "logos"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "logos", "scale.svg"), []byte("<svg/>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	specPath := filepath.Join(dir, "badges.yaml")
+	writeSpec := func(status string) {
+		spec := `badges:
+  - subject: license
+    status: MIT
+    color: blue
+    logo: logos/scale.svg
+    output: out/license.svg
+  - subject: build
+    status: ` + status + `
+    color: green
+    output: out/build.svg
+`
+		if err := ioutil.WriteFile(specPath, []byte(spec), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	license := filepath.Join(dir, "out", "license.svg")
+	build := filepath.Join(dir, "out", "build.svg")
+
+	writeSpec("passing")
+	result, err := RenderSpecFile(specPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result.Updated, []string{license, build}) || len(result.Unchanged) != 0 {
+		t.Errorf("expected both badges to be written, got %+v", result)
+	}
+	// the relative logo path is resolved against the spec directory and embedded
+	data, err := ioutil.ReadFile(license)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `xlink:href="data:image/svg&#43;xml;base64,PHN2Zy8&#43;"`) {
+		t.Errorf("expected the logo to be embedded, got %s", data)
+	}
+
+	result, err = RenderSpecFile(specPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Updated) != 0 || !reflect.DeepEqual(result.Unchanged, []string{license, build}) {
+		t.Errorf("expected both badges to be unchanged, got %+v", result)
+	}
+
+	writeSpec("failing")
+	result, err = RenderSpecFile(specPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result.Updated, []string{build}) || !reflect.DeepEqual(result.Unchanged, []string{license}) {
+		t.Errorf("expected only the build badge to be updated, got %+v", result)
+	}
+}
+
+func TestSpecRenderErrors(t *testing.T) {
+	dir := t.TempDir()
+	spec := &Spec{Badges: []BadgeSpec{
+		{Subject: "no output"},
+		{Subject: "style", Style: "plastic", Output: "style.svg"},
+		{Subject: "logo", Logo: "missing.svg", Output: "logo.svg"},
+		{Subject: "script", Logo: "javascript:alert(1)", Output: "script.svg"},
+		{Subject: "ok", Status: "fine", Output: "ok.svg"},
+	}}
+
+	result, err := spec.Render(dir)
+	errs, ok := err.(BatchError)
+	if !ok || len(errs) != 4 {
+		t.Fatalf("expected a BatchError with 4 errors, got %v", err)
+	}
+	for i, prefix := range []string{"badge 0 (no output)", "badge 1 (style)", "badge 2 (logo)", "badge 3 (script)"} {
+		if !strings.HasPrefix(errs[i].Error(), prefix) {
+			t.Errorf("expected error %d to start with %q, got %s", i, prefix, errs[i])
+		}
+	}
+	if !strings.Contains(err.Error(), "; badge 1 (style)") {
+		t.Errorf("expected the errors to be joined, got %s", err)
+	}
+
+	// the badge that could be rendered still is
+	if !reflect.DeepEqual(result.Updated, []string{filepath.Join(dir, "ok.svg")}) {
+		t.Errorf("expected ok.svg to be written, got %+v", result)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "script.svg")); !os.IsNotExist(err) {
+		t.Errorf("expected script.svg not to be written, got %v", err)
+	}
+}
+
+func TestParseSpec(t *testing.T) {
+	spec, err := ParseSpec([]byte(`{"badges": [{"subject": "a", "status": "b", "output": "a.svg"}]}`), "json")
+	if err != nil || len(spec.Badges) != 1 || spec.Badges[0].Output != "a.svg" {
+		t.Errorf("expected one badge, got %+v, %v", spec, err)
+	}
+	if _, err := ParseSpec([]byte("badges: []"), "toml"); err == nil {
+		t.Errorf("expected an error for an unknown format")
+	}
+}
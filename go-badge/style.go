@@ -3,7 +3,7 @@ package badge
 import "strings"
 
 // TODO: Think of using a sort of SVG minifier after template was executed.
-var flatTemplate = strings.TrimSpace("<svg xmlns=\"http://www.w3.org/2000/svg\" xmlns:xlink=\"http://www.w3.org/1999/xlink\" width=\"{{.Bounds.Dx}}\" height=\"20\"><linearGradient id=\"smooth\" x2=\"0\" y2=\"100\"><stop offset=\"0\" stop-color=\"#bbb\" stop-opacity=\".1\"/><stop offset=\"1\" stop-opacity=\".1\"/></linearGradient><mask id=\"round\"><rect width=\"{{.Bounds.Dx}}\" height=\"20\" rx=\"3\" fill=\"#fff\"/></mask><g mask=\"url(#round)\"><rect width=\"{{.Bounds.SubjectDx}}\" height=\"20\" fill=\"#555\"/><rect x=\"{{.Bounds.SubjectDx}}\" width=\"{{.Bounds.StatusDx}}\" height=\"20\" fill=\"{{or .Color \"#4c1\" | html}}\"/><rect width=\"{{.Bounds.Dx}}\" height=\"20\" fill=\"url(#smooth)\"/></g><g fill=\"#fff\" text-anchor=\"middle\" font-family=\"DejaVu Sans,Verdana,Geneva,sans-serif\" font-size=\"11\"><text x=\"{{.Bounds.SubjectX}}\" y=\"15\" fill=\"#010101\" fill-opacity=\".3\">{{.Subject | html}}</text><text x=\"{{.Bounds.SubjectX}}\" y=\"14\">{{.Subject | html}}</text><text x=\"{{.Bounds.StatusX}}\" y=\"15\" fill=\"#010101\" fill-opacity=\".3\">{{.Status | html}}</text><text x=\"{{.Bounds.StatusX}}\" y=\"14\">{{.Status | html}}</text></g></svg>")
+var flatTemplate = strings.TrimSpace("<svg xmlns=\"http://www.w3.org/2000/svg\" xmlns:xlink=\"http://www.w3.org/1999/xlink\" width=\"{{.Bounds.Dx}}\" height=\"20\"><linearGradient id=\"smooth\" x2=\"0\" y2=\"100\"><stop offset=\"0\" stop-color=\"#bbb\" stop-opacity=\".1\"/><stop offset=\"1\" stop-opacity=\".1\"/></linearGradient><mask id=\"round\"><rect width=\"{{.Bounds.Dx}}\" height=\"20\" rx=\"3\" fill=\"#fff\"/></mask><g mask=\"url(#round)\"><rect width=\"{{.Bounds.SubjectDx}}\" height=\"20\" fill=\"#555\"/><rect x=\"{{.Bounds.SubjectDx}}\" width=\"{{.Bounds.StatusDx}}\" height=\"20\" fill=\"{{or .Color \"#4c1\" | html}}\"/><rect width=\"{{.Bounds.Dx}}\" height=\"20\" fill=\"url(#smooth)\"/></g>{{if .Logo}}<image x=\"5\" y=\"3\" width=\"14\" height=\"14\" xlink:href=\"{{.Logo}}\"/>{{end}}<g fill=\"#fff\" text-anchor=\"middle\" font-family=\"DejaVu Sans,Verdana,Geneva,sans-serif\" font-size=\"11\"><text x=\"{{.Bounds.SubjectX}}\" y=\"15\" fill=\"#010101\" fill-opacity=\".3\">{{.Subject | html}}</text><text x=\"{{.Bounds.SubjectX}}\" y=\"14\">{{.Subject | html}}</text><text x=\"{{.Bounds.StatusX}}\" y=\"15\" fill=\"#010101\" fill-opacity=\".3\">{{.Status | html}}</text><text x=\"{{.Bounds.StatusX}}\" y=\"14\">{{.Status | html}}</text></g></svg>")
 
 /*
 var flatTemplate = strings.TrimSpace(`
@@ -23,6 +23,8 @@ var flatTemplate = strings.TrimSpace(`
     <rect width="{{.Bounds.Dx}}" height="20" fill="url(#smooth)"/>
   </g>
 
+  {{if .Logo}}<image x="5" y="3" width="14" height="14" xlink:href="{{.Logo}}"/>{{end}}
+
   <g fill="#fff" text-anchor="middle" font-family="DejaVu Sans,Verdana,Geneva,sans-serif" font-size="11">
     <text x="{{.Bounds.SubjectX}}" y="15" fill="#010101" fill-opacity=".3">{{.Subject | html}}</text>
     <text x="{{.Bounds.SubjectX}}" y="14">{{.Subject | html}}</text>
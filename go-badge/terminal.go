@@ -0,0 +1,179 @@
+package badge
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// TerminalMode selects how a badge is drawn by RenderTerminal.
+type TerminalMode int
+
+const (
+	// TerminalAuto picks a mode from the writer and environment: plain text
+	// when w is not a TTY, truecolor when COLORTERM advertises it, 256 colors otherwise.
+	TerminalAuto TerminalMode = iota
+	// TerminalPlain renders the badge as [subject|status] without escape codes.
+	TerminalPlain
+	// Terminal256 renders the badge using the xterm 256 color palette.
+	Terminal256
+	// TerminalTrueColor renders the badge using 24-bit ANSI colors.
+	TerminalTrueColor
+)
+
+// subjectColor is the background of the subject half, matching the SVG template.
+const subjectColor = "#555"
+
+// defaultStatusColor is used when the badge color can't be parsed, matching the SVG template.
+const defaultStatusColor = "#4c1"
+
+type rgb struct {
+	R, G, B uint8
+}
+
+// RenderTerminal renders a badge of the given color, with given subject and status to w
+// using ANSI escape codes. It falls back to plain [subject|status] when w is not a TTY.
+func RenderTerminal(subject, status string, color Color, w io.Writer) error {
+	return RenderTerminalMode(subject, status, color, TerminalAuto, w)
+}
+
+// RenderTerminalMode is like RenderTerminal, but draws the badge using the given mode.
+func RenderTerminalMode(subject, status string, color Color, mode TerminalMode, w io.Writer) error {
+	if mode == TerminalAuto {
+		mode = detectTerminalMode(w)
+	}
+	_, err := io.WriteString(w, StringRenderTerminal(subject, status, color, mode))
+	return err
+}
+
+// StringRenderTerminal returns the terminal representation of a badge. TerminalAuto is
+// treated as if the output was not a TTY. Control characters are removed from subject and
+// status, so that text taken from, say, build output can't send escape codes.
+func StringRenderTerminal(subject, status string, color Color, mode TerminalMode) string {
+	subject, status = stripControl(subject), stripControl(status)
+	if mode != Terminal256 && mode != TerminalTrueColor {
+		return "[" + subject + "|" + status + "]"
+	}
+
+	subjectBg, _ := parseHexColor(subjectColor)
+	statusBg, ok := parseHexColor(color.String())
+	if !ok {
+		statusBg, _ = parseHexColor(defaultStatusColor)
+	}
+
+	var b strings.Builder
+	b.WriteString(ansiColors(subjectBg, mode))
+	b.WriteString(" " + subject + " ")
+	b.WriteString(ansiColors(statusBg, mode))
+	b.WriteString(" " + status + " ")
+	b.WriteString("\x1b[0m")
+	return b.String()
+}
+
+// stripControl returns s without control characters, such as ESC.
+func stripControl(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// detectTerminalMode inspects w and the environment to decide how to draw a badge.
+func detectTerminalMode(w io.Writer) TerminalMode {
+	f, ok := w.(*os.File)
+	if !ok {
+		return TerminalPlain
+	}
+	fi, err := f.Stat()
+	if err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+		return TerminalPlain
+	}
+	if _, ok := os.LookupEnv("NO_COLOR"); ok || os.Getenv("TERM") == "dumb" {
+		return TerminalPlain
+	}
+	switch strings.ToLower(os.Getenv("COLORTERM")) {
+	case "truecolor", "24bit":
+		return TerminalTrueColor
+	}
+	return Terminal256
+}
+
+// ansiColors returns the escape sequence setting bg as background, with a foreground
+// color picked to be readable on it.
+func ansiColors(bg rgb, mode TerminalMode) string {
+	fg := rgb{255, 255, 255}
+	if contrastRatio(bg, rgb{0, 0, 0}) > contrastRatio(bg, fg) {
+		fg = rgb{0, 0, 0}
+	}
+	if mode == TerminalTrueColor {
+		return fmt.Sprintf("\x1b[48;2;%d;%d;%dm\x1b[38;2;%d;%d;%dm", bg.R, bg.G, bg.B, fg.R, fg.G, fg.B)
+	}
+	return fmt.Sprintf("\x1b[48;5;%dm\x1b[38;5;%dm", xterm256(bg), xterm256(fg))
+}
+
+// parseHexColor parses colors of the form #rgb or #rrggbb, with or without the leading #.
+func parseHexColor(s string) (rgb, bool) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "#")
+	if len(s) == 3 {
+		s = string([]byte{s[0], s[0], s[1], s[1], s[2], s[2]})
+	}
+	if len(s) != 6 {
+		return rgb{}, false
+	}
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return rgb{}, false
+	}
+	return rgb{uint8(v >> 16), uint8(v >> 8), uint8(v)}, true
+}
+
+// luminance returns the WCAG relative luminance of c.
+func luminance(c rgb) float64 {
+	channel := func(v uint8) float64 {
+		f := float64(v) / 255
+		if f <= 0.03928 {
+			return f / 12.92
+		}
+		return math.Pow((f+0.055)/1.055, 2.4)
+	}
+	return 0.2126*channel(c.R) + 0.7152*channel(c.G) + 0.0722*channel(c.B)
+}
+
+// contrastRatio returns the WCAG contrast ratio between a and b.
+func contrastRatio(a, b rgb) float64 {
+	la, lb := luminance(a), luminance(b)
+	if la < lb {
+		la, lb = lb, la
+	}
+	return (la + 0.05) / (lb + 0.05)
+}
+
+// cubeLevels are the channel intensities of the xterm 6x6x6 color cube.
+var cubeLevels = []int{0, 95, 135, 175, 215, 255}
+
+// xterm256 returns the index of the closest color in the xterm 256 color cube.
+func xterm256(c rgb) int {
+	nearest := func(v uint8) int {
+		best := 0
+		for i, l := range cubeLevels {
+			if abs(int(v)-l) < abs(int(v)-cubeLevels[best]) {
+				best = i
+			}
+		}
+		return best
+	}
+	return 16 + 36*nearest(c.R) + 6*nearest(c.G) + nearest(c.B)
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
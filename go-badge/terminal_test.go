@@ -0,0 +1,97 @@
+package badge
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestStringRenderTerminal(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		color    Color
+		mode     TerminalMode
+		expected string
+	}{
+		{"plain", ColorBlue, TerminalPlain, "[build|passing]"},
+		{"auto is plain", ColorBlue, TerminalAuto, "[build|passing]"},
+		{"256 colors", ColorBlue, Terminal256,
+			"\x1b[48;5;59m\x1b[38;5;231m build \x1b[48;5;32m\x1b[38;5;16m passing \x1b[0m"},
+		{"truecolor", ColorBlue, TerminalTrueColor,
+			"\x1b[48;2;85;85;85m\x1b[38;2;255;255;255m build \x1b[48;2;0;126;198m\x1b[38;2;0;0;0m passing \x1b[0m"},
+		{"hex color", Color("#804000"), TerminalTrueColor,
+			"\x1b[48;2;85;85;85m\x1b[38;2;255;255;255m build \x1b[48;2;128;64;0m\x1b[38;2;255;255;255m passing \x1b[0m"},
+		{"unknown color", Color("notacolor"), TerminalTrueColor,
+			"\x1b[48;2;85;85;85m\x1b[38;2;255;255;255m build \x1b[48;2;68;204;17m\x1b[38;2;0;0;0m passing \x1b[0m"},
+	} {
+		if s := StringRenderTerminal("build", "passing", test.color, test.mode); s != test.expected {
+			t.Errorf("%s: expected %q, got %q", test.name, test.expected, s)
+		}
+	}
+}
+
+func TestStringRenderTerminalControlCharacters(t *testing.T) {
+	status := "fail\x1b]0;pwned\x07ed\x1b[2J\r\n\u009b31m"
+	for mode, expected := range map[TerminalMode]string{
+		TerminalPlain: "[build|fail]0;pwneded[2J31m]",
+		Terminal256:   "\x1b[48;5;59m\x1b[38;5;231m build \x1b[48;5;32m\x1b[38;5;16m fail]0;pwneded[2J31m \x1b[0m",
+	} {
+		if s := StringRenderTerminal("build\x1b", status, ColorBlue, mode); s != expected {
+			t.Errorf("%v: expected %q, got %q", mode, expected, s)
+		}
+	}
+}
+
+func TestRenderTerminalNotATTY(t *testing.T) {
+	var buf bytes.Buffer
+	if err := RenderTerminal("build", "passing", ColorGreen, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "[build|passing]" {
+		t.Errorf("expected plain output for a buffer, got %q", buf.String())
+	}
+}
+
+func TestTerminalColors(t *testing.T) {
+	for _, test := range []struct {
+		color Color
+		hex   string
+		rgb   rgb
+		ok    bool
+	}{
+		{ColorBrightgreen, "#4c1", rgb{68, 204, 17}, true},
+		{ColorBlue, "#007ec6", rgb{0, 126, 198}, true},
+		{ColorGrey, "#555", rgb{85, 85, 85}, true},
+		{Color("#abc"), "#abc", rgb{170, 187, 204}, true},
+		{Color("ff8000"), "ff8000", rgb{255, 128, 0}, true},
+		{Color("#12345"), "#12345", rgb{}, false},
+		{Color("zzzzzz"), "zzzzzz", rgb{}, false},
+	} {
+		if test.color.String() != test.hex {
+			t.Errorf("%s: expected %s, got %s", string(test.color), test.hex, test.color.String())
+		}
+		c, ok := parseHexColor(test.color.String())
+		if c != test.rgb || ok != test.ok {
+			t.Errorf("%s: expected %v, %v, got %v, %v", string(test.color), test.rgb, test.ok, c, ok)
+		}
+	}
+}
+
+func TestTerminalTextContrast(t *testing.T) {
+	for _, test := range []struct {
+		bg     string
+		colors string
+	}{
+		// dark backgrounds get white text, light ones black
+		{"#555", "\x1b[48;2;85;85;85m\x1b[38;2;255;255;255m"},
+		{"#804000", "\x1b[48;2;128;64;0m\x1b[38;2;255;255;255m"},
+		{"#4c1", "\x1b[48;2;68;204;17m\x1b[38;2;0;0;0m"},
+		{"#dfb317", "\x1b[48;2;223;179;23m\x1b[38;2;0;0;0m"},
+		{"#fff", "\x1b[48;2;255;255;255m\x1b[38;2;0;0;0m"},
+		{"#000", "\x1b[48;2;0;0;0m\x1b[38;2;255;255;255m"},
+	} {
+		bg, _ := parseHexColor(test.bg)
+		if s := ansiColors(bg, TerminalTrueColor); s != test.colors {
+			t.Errorf("%s: expected %q, got %q", test.bg, test.colors, s)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// UnmarshalError is returned by Unmarshal when one or more fields could not be populated.
+// It lists every missing required key and every value that could not be converted, so all
+// configuration problems can be reported at once.
+type UnmarshalError struct {
+	// Missing holds the keys of required fields that have no value and no default.
+	Missing []string
+	// Invalid holds an error for each value that could not be converted to its field's type.
+	Invalid []error
+}
+
+func (e *UnmarshalError) Error() string {
+	var parts []string
+	if len(e.Missing) > 0 {
+		parts = append(parts, "missing required keys: "+strings.Join(e.Missing, ", "))
+	}
+	for _, err := range e.Invalid {
+		parts = append(parts, err.Error())
+	}
+	return "config: " + strings.Join(parts, "; ")
+}
+
+// Unmarshal populates the struct pointed to by out from the keys under prefix ("" for the
+// top level). Fields are read from the key named by their `config` tag, relative to prefix,
+// or from the field name (compared case-insensitively) if there is no tag. A tag of "-"
+// skips the field. Nested structs read the keys under their own name, so with
+//
+//	type DB struct {
+//		Host    string        `config:"host" default:"localhost"`
+//		Port    int           `config:"port" required:"true"`
+//		Timeout time.Duration `config:"timeout" default:"30s"`
+//	}
+//	type App struct {
+//		DB DB `config:"db"`
+//	}
+//
+// conf.Unmarshal("", &app) reads app.DB.Host from "db.host". Values are converted to the
+// field type: ints, uints, floats, bools, strings, time.Duration ("30s", or a number of
+// seconds), time.Time (RFC 3339), slices (from arrays or comma-separated strings), maps
+// with string keys, pointers and types implementing encoding.TextUnmarshaler.
+// If a key is missing, the `default` tag is used if present; otherwise the field is left
+// unchanged, or reported as missing if it is tagged `required:"true"`.
+// All problems are collected and returned together as an *UnmarshalError.
+func (c Config) Unmarshal(prefix string, out interface{}) error {
+	rv := reflect.ValueOf(out)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("config: Unmarshal needs a non-nil pointer to a struct, got %T", out)
+	}
+
+	errs := &UnmarshalError{}
+	c.bindStruct(prefix, rv.Elem(), errs)
+	if len(errs.Missing) > 0 || len(errs.Invalid) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (c Config) bindStruct(prefix string, sv reflect.Value, errs *UnmarshalError) {
+	st := sv.Type()
+	for i := 0; i < st.NumField(); i++ {
+		f := st.Field(i)
+		if f.PkgPath != "" {
+			// unexported
+			continue
+		}
+		name := f.Tag.Get("config")
+		if name == "-" {
+			continue
+		}
+		fv := sv.Field(i)
+
+		// embedded structs without a tag share the prefix of their parent
+		if f.Anonymous && name == "" && isNestedStruct(f.Type) {
+			c.bindStruct(prefix, fv, errs)
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+		key := joinKey(prefix, name)
+
+		if isNestedStruct(f.Type) {
+			c.bindStruct(key, fv, errs)
+			continue
+		}
+		if f.Type.Kind() == reflect.Ptr && isNestedStruct(f.Type.Elem()) {
+			if fv.IsNil() {
+				fv.Set(reflect.New(f.Type.Elem()))
+			}
+			c.bindStruct(key, fv.Elem(), errs)
+			continue
+		}
+
+		v, found, e := c.lookupField(key, f.Type)
+		if e != nil {
+			errs.Invalid = append(errs.Invalid, e)
+			continue
+		}
+		if !found {
+			if def, ok := f.Tag.Lookup("default"); ok {
+				v = def
+			} else {
+				if f.Tag.Get("required") == "true" {
+					errs.Missing = append(errs.Missing, key)
+				}
+				continue
+			}
+		}
+
+		if e := convertInto(v, fv); e != nil {
+			errs.Invalid = append(errs.Invalid, fmt.Errorf("%s: %s", key, e))
+		}
+	}
+}
+
+// isNestedStruct reports whether values of t are populated field by field, rather than
+// converted from a single config value.
+func isNestedStruct(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t != timeType &&
+		!reflect.PtrTo(t).Implements(textUnmarshalerType)
+}
+
+// lookupField finds the value for a field. Maps are assembled from all keys under key,
+// since nestedMerge flattens nested objects. Lookups fall back to a case-insensitive match
+// so that untagged fields such as Host find "host". The error is set if the value can't
+// be expanded.
+func (c Config) lookupField(key string, t reflect.Type) (interface{}, bool, error) {
+	if t.Kind() == reflect.Map {
+		m, e := c.subMap(key)
+		return m, len(m) > 0, e
+	}
+
+	if k, ok := c.foldKey(key); ok {
+		key = k
+	}
+	v, e := c.value(key)
+	if errors.Is(e, ErrNotFound) {
+		return nil, false, nil
+	}
+	return v, e == nil, e
+}
+
+// foldKey returns the key that matches key case-insensitively, if key itself isn't set.
+// If several do, the first in sorted order is used, so the choice is always the same.
+func (c Config) foldKey(key string) (string, bool) {
+	values := c.view()
+	if _, ok := values[key]; ok {
+		return key, true
+	}
+	for _, k := range sortedKeys(values) {
+		if strings.EqualFold(k, key) {
+			return k, true
+		}
+	}
+	return "", false
+}
+
+// subMap returns the values of all keys under prefix, keyed by the rest of the key, with
+// references expanded.
+func (c Config) subMap(prefix string) (map[string]interface{}, error) {
+	p := strings.ToLower(prefix) + "."
+	m := make(map[string]interface{})
+	for k := range c.view() {
+		if strings.HasPrefix(strings.ToLower(k), p) {
+			v, e := c.value(k)
+			if e != nil {
+				return nil, e
+			}
+			m[k[len(p):]] = v
+		}
+	}
+	return m, nil
+}
+
+func joinKey(prefix string, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
@@ -0,0 +1,110 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+type child2Settings struct {
+	MenuTTL       int           `config:"menuTTL"`
+	SiteConfigTTL time.Duration `config:"siteConfigTTL"`
+	SiteTreeTTL   float64
+}
+
+type test1Settings struct {
+	IntProp   int64    `config:"intprop"`
+	StrProp   string   `config:"strprop"`
+	BoolProp  bool     `config:"boolprop"`
+	ArrayProp []string `config:"arrayprop"`
+	Child1    struct {
+		X    string `config:"child1_2.x"`
+		Prop uint   `config:"child1prop"`
+	} `config:"child1"`
+	Child2   child2Settings `config:"child2"`
+	Timeout  time.Duration  `config:"timeout" default:"1m30s"`
+	Started  time.Time      `config:"started" default:"2016-01-02T15:04:05Z"`
+	Labels   map[string]string
+	Optional string `config:"optional"`
+	Ignored  string `config:"-"`
+}
+
+func TestUnmarshal(t *testing.T) {
+	conf, _ := ReadFromFile("./test.json")
+	conf.AddDefault("test1.labels.env", "prod")
+	conf.AddDefault("test1.labels.team", "web")
+
+	s := test1Settings{Optional: "keep", Ignored: "keep"}
+	e := conf.Unmarshal("test1", &s)
+	if e != nil {
+		t.Fatalf("Error unmarshalling test1: '%s'", e)
+	}
+
+	if s.IntProp != 123 || s.StrProp != "str" || !s.BoolProp {
+		t.Errorf("Expected intprop, strprop and boolprop to be 123, \"str\" and true, got %v", s)
+	}
+	if len(s.ArrayProp) != 3 || s.ArrayProp[2] != "999" {
+		t.Errorf("Expected arrayprop to be [a b 999], got %v", s.ArrayProp)
+	}
+	if s.Child1.X != "c1_2" || s.Child1.Prop != 101 {
+		t.Errorf("Expected child1 to be {c1_2 101}, got %v", s.Child1)
+	}
+	if s.Child2.MenuTTL != 30 || s.Child2.SiteConfigTTL != 30*time.Second || s.Child2.SiteTreeTTL != 30 {
+		t.Errorf("Expected child2 TTLs to be 30, got %v", s.Child2)
+	}
+	if s.Timeout != 90*time.Second {
+		t.Errorf("Expected timeout to default to 1m30s, got %s", s.Timeout)
+	}
+	if !s.Started.Equal(time.Date(2016, 1, 2, 15, 4, 5, 0, time.UTC)) {
+		t.Errorf("Expected started to default to 2016-01-02T15:04:05Z, got %s", s.Started)
+	}
+	if len(s.Labels) != 2 || s.Labels["env"] != "prod" || s.Labels["team"] != "web" {
+		t.Errorf("Expected labels to be map[env:prod team:web], got %v", s.Labels)
+	}
+	if s.Optional != "keep" || s.Ignored != "keep" {
+		t.Errorf("Expected optional and ignored fields to be left alone, got %q and %q", s.Optional, s.Ignored)
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	conf, _ := ReadFromFile("./test.json")
+
+	var s struct {
+		StrProp   int    `config:"strprop"`
+		IntProp   bool   `config:"intprop"`
+		Missing   string `config:"missing" required:"true"`
+		Defaulted string `config:"defaulted" required:"true" default:"x"`
+	}
+	e := conf.Unmarshal("test1", &s)
+	ue, ok := e.(*UnmarshalError)
+	if !ok {
+		t.Fatalf("Expected an *UnmarshalError, got '%v'", e)
+	}
+	if len(ue.Missing) != 1 || ue.Missing[0] != "test1.missing" {
+		t.Errorf("Expected test1.missing to be reported missing, got %v", ue.Missing)
+	}
+	if len(ue.Invalid) != 2 {
+		t.Errorf("Expected two conversion errors, got %v", ue.Invalid)
+	}
+
+	if conf.Unmarshal("", s) == nil {
+		t.Errorf("Expected an error unmarshalling into a non-pointer, didn't get one")
+	}
+}
+
+func TestUnmarshalFoldedKeys(t *testing.T) {
+	// keys differing only in case are matched in sorted order, so always the same way
+	for i := 0; i < 20; i++ {
+		conf := NewConfig()
+		conf.AddDefault("db.port", "1")
+		conf.AddDefault("db.PORT", "2")
+		conf.AddDefault("db.pOrt", "3")
+
+		var s struct{ Port int }
+		if e := conf.Unmarshal("db", &s); e != nil {
+			t.Fatal(e)
+		}
+		if s.Port != 2 {
+			t.Fatalf("Expected Port to be read from db.PORT, got %d", s.Port)
+		}
+	}
+}
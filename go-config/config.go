@@ -1,22 +1,97 @@
-// Config package provides an implementation of ConfigProvider. Currently it only reads from a JSON file, but
-// this may be extended to support other forms.
+// Config package merges configuration from several sources into one set of dot-delimited
+// keys. It reads from JSON, YAML, TOML, INI and Java properties files, from environment
+// variables and command-line flags, and from any Provider, such as an HTTP endpoint.
 package config
 
 import (
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"os"
 	"reflect"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
-// Concrete storage for the configuration values read. The map keys are dot-delimited.
-type Config map[string]interface{}
+// Config is the set of configuration values read. Keys are dot-delimited. A Config is a
+// handle to shared state, so copies of it see the same values, and it is safe to use
+// from several goroutines while values are added or reloaded. Reads don't take a lock: they
+// use an immutable snapshot of the values, which writes replace atomically. Use Snapshot to
+// read several keys from the same version of the config. The zero value is not usable;
+// create one with NewConfig or one of the Read* helpers. Config used to be a map; the
+// README shows how to update code that indexed or ranged over it.
+type Config struct {
+	s *store
+	// prefix is set for views created by Sub. Keys used with the view are relative to it.
+	prefix string
+}
+
+// store holds the state shared by copies of a Config.
+type store struct {
+	// values holds the current map of values. A map is never modified once it has been
+	// stored here; writers build a new one and store it, so readers need no lock.
+	values atomic.Pointer[map[string]interface{}]
+
+	// mu serialises writers, and guards the fields below.
+	mu        sync.RWMutex
+	layers    []*layer
+	listeners []func(changed []string)
+
+	lastReload    time.Time
+	lastReloadErr error
+
+	// decrypter decrypts "enc:" values; see SetDecrypter.
+	decrypter Decrypter
+	// secretRefs holds the kinds of source whose secret references are revealed; see
+	// EnableSecretRefs. The map is replaced, not modified.
+	secretRefs map[string]bool
+	// secretCache holds the secrets read so far, by reference, and secretGen counts the
+	// times it has been cleared.
+	secretCache map[string]string
+	secretGen   uint64
+	// logger receives debug messages; see SetLogger.
+	logger Logger
+	// sensitive holds the patterns of keys to redact, or nil for the defaults.
+	sensitive []string
+	// schema, if set, is checked by Reload before new values replace the old.
+	schema *Schema
+	// arrayMerges holds the strategies for merging arrays; see SetArrayMerge.
+	arrayMerges arrayMerges
+}
+
+// Kinds of source a value can come from, as reported by Explain.
+const (
+	SourceFile    = "file"
+	SourceEnv     = "env"
+	SourceDefault = "default"
+	SourceFlag    = "flag"
+)
+
+// layer records one source merged into a Config (a file, the environment, a default), in
+// the order they were added. Replaying the layers in order rebuilds the merged values.
+type layer struct {
+	kind     string
+	name     string
+	prefix   string
+	override bool
+	// values are the flattened, dot-delimited values this source contributed.
+	values map[string]interface{}
+	// origins optionally names where each key came from within the source, such as
+	// the environment variable it was read from.
+	origins map[string]string
+	// includes lists the files included by a file with "$include", so they can be watched.
+	includes []string
+	// provider is set for layers that can be read again, such as those added with AddFile
+	// or AddProvider.
+	provider Provider
+	// parts are the layers this one is made of, such as the files of a directory, which
+	// are merged in order instead of values.
+	parts []*layer
+}
 
 // Create a new, empty Config
 func NewConfig() Config {
-	return make(map[string]interface{})
+	return Config{s: newStore(make(map[string]interface{}))}
 }
 
 // ReadFromFile is a helper that reads the configuration from JSON in the provided path with default
@@ -46,29 +121,27 @@ func ReadFromEnv(prefix string) Config {
 // entries occur at the top level.
 // If settings in this file already exist in config, override determines whether the new settings
 // will override existing settings (yes if true, no if false.)
-// The config file is JSON and will generally contain a single object. The properties of that
-// object form the top-level name space for Get().
+// The format of the file is determined by its extension (see FormatForPath); files without a
+// recognised extension are read as JSON. The file will generally contain a single object
+// (or mapping, or set of sections), whose properties form the top-level name space for Get().
+// A file can include others with an "$include" property listing their paths; see AddDirectory.
 func (c Config) AddFile(path string, destPrefix string, override bool) error {
-	// read file
-	data, e := ioutil.ReadFile(path)
-	if e != nil {
-		return e
-	}
+	return c.AddFileWithFormat(path, FormatForPath(path), destPrefix, override)
+}
 
-	// decode json
-	var v interface{}
-	e = json.Unmarshal(data, &v)
+// AddFileWithFormat is like AddFile, but reads the file in the given format (one of the
+// Format* constants) regardless of its extension. Whatever the format, nested properties
+// are flattened into the same dot-delimited keys, so a "host" property inside "db" is read
+// with Get("db.host").
+func (c Config) AddFileWithFormat(path string, format string, destPrefix string, override bool) error {
+	// read and decode the file, and any files it includes
+	l, e := c.addProvider(FileProvider{Path: path, Format: format}, destPrefix, override)
 	if e != nil {
 		return e
 	}
-
-	// Get this as a map
-	nested := v.(map[string]interface{})
-
-	c.nestedMerge(nested, destPrefix, override)
+	c.debugf("config: AddFile(%q): read %d keys as %s", path, len(l.values), format)
 
 	return nil
-
 }
 
 // AddEnvironment will add configuration properties from the environment. Only environment
@@ -81,12 +154,11 @@ func (c Config) AddFile(path string, destPrefix string, override bool) error {
 func (c Config) AddEnvironment(sourcePrefix string, destPrefix string, override bool) {
 	envs := os.Environ()
 	nested := make(map[string]interface{})
+	origins := make(map[string]string)
 
-	fmt.Printf("AddEnvironment: %s\n", envs)
 	for _, x := range envs {
 		// split on =
 		kv := strings.SplitN(x, "=", 2)
-		fmt.Printf("kv=%s\n", kv)
 
 		// if name starts with the prefix, add it to nested for merging
 		if len(kv) >= 1 && strings.HasPrefix(kv[0], sourcePrefix) {
@@ -98,47 +170,200 @@ func (c Config) AddEnvironment(sourcePrefix string, destPrefix string, override
 				v = ""
 			}
 			nested[kv[0]] = v
+			origins[joinKey(destPrefix, kv[0])] = kv[0]
 		}
 	}
 
-	fmt.Printf("AddEnvironment: nested: %s\n", nested)
+	c.debugf("config: AddEnvironment(%q): found %s", sourcePrefix, sortedKeys(nested))
 	// merge it into the config
-	c.nestedMerge(nested, destPrefix, override)
+	c.merge(&layer{kind: SourceEnv, name: sourcePrefix, prefix: destPrefix, override: override, origins: origins}, nested)
 }
 
+// nestedMerge merges object into the config as a layer of default values.
 func (c Config) nestedMerge(object map[string]interface{}, prefix string, override bool) {
+	c.merge(&layer{kind: SourceDefault, prefix: prefix, override: override}, object)
+}
+
+// merge flattens object into l and adds l on top of the existing layers.
+func (c Config) merge(l *layer, object map[string]interface{}) {
+	l.prefix = c.full(l.prefix)
+	l.values = make(map[string]interface{})
+	flatten(object, l.prefix, l.values)
+	if c.prefix != "" && l.origins != nil {
+		origins := make(map[string]string, len(l.origins))
+		for k, origin := range l.origins {
+			origins[c.full(k)] = origin
+		}
+		l.origins = origins
+	}
+	c.add(l)
+}
+
+// add merges the values of l into the config, and records l so it can be replayed.
+func (c Config) add(l *layer) {
+	c.s.mu.Lock()
+	defer c.s.mu.Unlock()
+
+	old := c.snapshot()
+	values := make(map[string]interface{}, len(old)+len(l.values))
+	for k, v := range old {
+		values[k] = v
+	}
+	l.apply(values, c.s.arrayMerges)
+	c.s.layers = append(c.s.layers, l)
+	c.s.values.Store(&values)
+}
+
+// flatten adds the properties of object to result under dot-delimited keys.
+func flatten(object map[string]interface{}, prefix string, result map[string]interface{}) {
 	p := prefix + "."
 	if p == "." {
 		p = ""
 	}
 
 	for k, v := range object {
-		if reflect.TypeOf(v).Kind() == reflect.Map {
+		if m, ok := v.(map[string]interface{}); ok {
 			// if 'v' is a map of interface{}, recursively add.
-			c.nestedMerge(v.(map[string]interface{}), p+k, override)
+			flatten(m, p+k, result)
 		} else {
-			// otherwise just add the property, using the prefix. If the value exists, use
-			// override.
-			_, exists := c[p+k]
-			if !exists || override {
-				c[p+k] = v
-			}
+			// otherwise just add the property, using the prefix.
+			result[p+k] = v
+		}
+	}
+}
+
+// apply merges the values of the layer into values. If a value exists, use override,
+// unless both are arrays to be combined according to merges. Possible secret references
+// are marked with the kind of the layer.
+func (l *layer) apply(values map[string]interface{}, merges arrayMerges) {
+	for _, part := range l.parts {
+		part.apply(values, merges)
+	}
+	for k, v := range l.values {
+		v = markSecretRef(v, l.kind)
+		old, exists := values[k]
+		if !exists {
+			values[k] = v
+			continue
+		}
+		lower, higher := old, v
+		if !l.override {
+			lower, higher = v, old
+		}
+		if merged, ok := merges.merge(k, lower, higher); ok {
+			values[k] = merged
+		} else if l.override {
+			values[k] = v
 		}
 	}
 }
 
+// flatLayers returns layers with any made of parts replaced by their parts.
+func flatLayers(layers []*layer) []*layer {
+	var result []*layer
+	for _, l := range layers {
+		if l.parts != nil {
+			result = append(result, flatLayers(l.parts)...)
+		} else {
+			result = append(result, l)
+		}
+	}
+	return result
+}
+
+// newStore returns a store holding values.
+func newStore(values map[string]interface{}) *store {
+	s := &store{}
+	s.values.Store(&values)
+	return s
+}
+
+// snapshot returns the current values. The map must not be modified.
+func (c Config) snapshot() map[string]interface{} {
+	return *c.s.values.Load()
+}
+
+// Snapshot returns a copy of the config as it is now, which later changes to c (including
+// reloads) don't affect. Reading several keys from a snapshot, e.g. for the duration of a
+// request, gives values that are consistent with each other. Taking a snapshot is cheap:
+// the values themselves are shared, not copied.
+func (c Config) Snapshot() Config {
+	c.s.mu.RLock()
+	defer c.s.mu.RUnlock()
+
+	s := newStore(c.snapshot())
+	s.layers = make([]*layer, len(c.s.layers))
+	for i, l := range c.s.layers {
+		// Reload replaces the values of layers, so the snapshot needs its own
+		copied := *l
+		s.layers[i] = &copied
+	}
+	s.lastReload, s.lastReloadErr = c.s.lastReload, c.s.lastReloadErr
+	s.decrypter, s.logger, s.sensitive, s.schema = c.s.decrypter, c.s.logger, c.s.sensitive, c.s.schema
+	s.secretRefs = c.s.secretRefs
+	s.arrayMerges = c.s.arrayMerges
+	return Config{s: s, prefix: c.prefix}
+}
+
+// full returns the key in the underlying config for a key used with this view.
+func (c Config) full(key string) string {
+	if c.prefix == "" {
+		return key
+	}
+	if key == "" {
+		return c.prefix
+	}
+	return c.prefix + "." + key
+}
+
+// view returns the current values visible through this view, keyed relative to its
+// prefix. The map must not be modified.
+func (c Config) view() map[string]interface{} {
+	return c.viewOf(c.snapshot())
+}
+
+// viewOf returns the values visible through this view from a snapshot.
+func (c Config) viewOf(values map[string]interface{}) map[string]interface{} {
+	if c.prefix == "" {
+		return values
+	}
+	p := c.prefix + "."
+	result := make(map[string]interface{})
+	for k, v := range values {
+		if strings.HasPrefix(k, p) {
+			result[k[len(p):]] = v
+		}
+	}
+	return result
+}
+
+// lookup returns the value of key and whether it is set. Keys can index into arrays, and
+// references in values are expanded; see Get. Values that can't be expanded are treated as
+// not set.
+func (c Config) lookup(key string) (interface{}, bool) {
+	v, e := c.value(key)
+	return v, e == nil
+}
+
 // Get looks up an object in the map via a key. The key can have "." separators for names;
 // this will go into the structure as appropriate. It will return nil if a key maps to an undefined
 // property, or where a partial key is not an object.
+// Arrays can be indexed, so "servers[0].host" reads the host property of the first object
+// in the servers array, and "servers[*].host" returns a list of the host of every server.
+// String values can refer to other keys and to environment variables, as "${other.key}",
+// "${ENV:NAME}" or "${ENV:NAME:-default}"; these are expanded when the value is read, so
+// they see values added later. Get returns nil if a value can't be expanded; the ...E
+// getters return the *InterpolationError.
 func (c Config) Get(key string) interface{} {
-	return c[key]
+	v, _ := c.lookup(key)
+	return v
 }
 
 func (c Config) GetKeyAsStringArray(key string) []string {
 	tmp := make([]string,0)
-	switch reflect.TypeOf(c[key]).Kind() {
+	switch reflect.TypeOf(c.Get(key)).Kind() {
 		case reflect.Slice:
-			s := reflect.ValueOf(c[key])
+			s := reflect.ValueOf(c.Get(key))
 
 			for i := 0; i < s.Len(); i++ {
 				tmp = append(tmp, fmt.Sprintf("%v",s.Index(i)))
@@ -148,47 +373,7 @@ func (c Config) GetKeyAsStringArray(key string) []string {
 }
 
 func (c Config) HasKey(key string) bool {
-	return c[key] != nil
-}
-
-// AsString returns a key from the configuration (using Get), but returning it as a string.
-// If the key is not defined, it returns "".
-func (c Config) GetString(key string) string {
-	v := c.Get(key)
-	if v == nil {
-		return ""
-	}
-	return fmt.Sprintf("%v", v)
-}
-
-// AsInt returns a key from the configuration as an integer. Integer values in the
-// json file are retrieved as float64. This will return an error if it's not a float64.
-// Otherwise will convert it to an int (with truncation).
-func (c Config) GetInt(key string) int {
-	v := c.Get(key)
-	vv, ok := v.(float64)
-	if !ok {
-		return 0
-	}
-	return int(vv)
-}
-
-func (c Config) GetBool(key string) bool {
-	v := c.Get(key)
-	vv, ok := v.(bool)
-	if !ok {
-		return false
-	}
-	return bool(vv)
-}
-
-func (c Config) GetStringArray(key string) []string {
-	v := c.Get(key)
-	vv, ok := v.([]string)
-	if !ok {
-		return []string{}
-	}
-	return []string(vv)
+	return c.Get(key) != nil
 }
 
 func (c Config) AddDefault(key string, value string) (bool,error) {
@@ -16,7 +16,7 @@ func TestBasicProps(t *testing.T) {
 	if !ok {
 		t.Errorf("Expected test1.intprop to be a float")
 	} else if ii != 123.0 {
-		t.Errorf("Expected test1.intprop to have value 123, has %s", ii)
+		t.Errorf("Expected test1.intprop to have value 123, has %v", ii)
 	}
 
 	// read strprop
@@ -25,7 +25,7 @@ func TestBasicProps(t *testing.T) {
 	if !ok {
 		t.Errorf("Expected test1.strprop to be a string")
 	} else if ss != "str" {
-		t.Errorf("Expected test1.strprop to have value 'str', has '%s'", ii)
+		t.Errorf("Expected test1.strprop to have value 'str', has '%s'", ss)
 	}
 
 	// read boolprop
@@ -52,7 +52,7 @@ func TestAsInt(t *testing.T) {
 	if e != nil {
 		t.Errorf("Error reading test1.intprop using AsInt")
 	} else if i != 123 {
-		t.Errorf("Expected test1.intprop to have value 123, has %s", i)
+		t.Errorf("Expected test1.intprop to have value 123, has %d", i)
 	}
 
 	// test stringprop using GetInt, expecting an error
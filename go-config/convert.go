@@ -0,0 +1,391 @@
+package config
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	timeType            = reflect.TypeOf(time.Time{})
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// timeLayouts are the layouts tried, in order, when converting a string to a time.Time.
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// convertInto converts v, a value as stored in Config, to the type of rv and stores it
+// there. Values read from files are float64 (or int64 or uint64 for large integers), bool,
+// string, []interface{} or map[string]interface{}; strings (e.g. from the environment or an
+// INI file) are parsed. Numbers that don't fit in the target type are errors, not truncated.
+func convertInto(v interface{}, rv reflect.Value) error {
+	t := rv.Type()
+	if v == nil {
+		return fmt.Errorf("no value to convert to %s", t)
+	}
+
+	if t.Kind() != reflect.Interface && reflect.TypeOf(v).AssignableTo(t) {
+		rv.Set(reflect.ValueOf(v))
+		return nil
+	}
+
+	if s, ok := v.(string); ok && rv.CanAddr() && rv.Addr().Type().Implements(textUnmarshalerType) {
+		return rv.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+
+	switch t {
+	case durationType:
+		d, e := toDuration(v)
+		if e != nil {
+			return e
+		}
+		rv.SetInt(int64(d))
+		return nil
+	case timeType:
+		tm, e := toTime(v)
+		if e != nil {
+			return e
+		}
+		rv.Set(reflect.ValueOf(tm))
+		return nil
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		n := reflect.New(t.Elem())
+		if e := convertInto(v, n.Elem()); e != nil {
+			return e
+		}
+		rv.Set(n)
+	case reflect.Interface:
+		rv.Set(reflect.ValueOf(v))
+	case reflect.String:
+		switch v.(type) {
+		case []interface{}, map[string]interface{}:
+			return fmt.Errorf("cannot convert %T to string", v)
+		}
+		rv.SetString(fmt.Sprintf("%v", v))
+	case reflect.Bool:
+		b, e := toBool(v)
+		if e != nil {
+			return e
+		}
+		rv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, e := toInt64(v)
+		if e != nil {
+			return e
+		}
+		if rv.OverflowInt(i) {
+			return fmt.Errorf("%d overflows %s", i, t)
+		}
+		rv.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, e := toUint64(v)
+		if e != nil {
+			return e
+		}
+		if rv.OverflowUint(u) {
+			return fmt.Errorf("%d overflows %s", u, t)
+		}
+		rv.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, e := toFloat64(v)
+		if e != nil {
+			return e
+		}
+		if rv.OverflowFloat(f) {
+			return fmt.Errorf("%g overflows %s", f, t)
+		}
+		rv.SetFloat(f)
+	case reflect.Slice:
+		items, e := toSlice(v)
+		if e != nil {
+			return e
+		}
+		s := reflect.MakeSlice(t, len(items), len(items))
+		for i, item := range items {
+			if e := convertInto(item, s.Index(i)); e != nil {
+				return fmt.Errorf("element %d: %s", i, e)
+			}
+		}
+		rv.Set(s)
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return fmt.Errorf("unsupported map key type %s", t.Key())
+		}
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("cannot convert %T to %s", v, t)
+		}
+		result := reflect.MakeMapWithSize(t, len(m))
+		for k, item := range m {
+			ev := reflect.New(t.Elem()).Elem()
+			if e := convertInto(item, ev); e != nil {
+				return fmt.Errorf("key %q: %s", k, e)
+			}
+			result.SetMapIndex(reflect.ValueOf(k).Convert(t.Key()), ev)
+		}
+		rv.Set(result)
+	case reflect.Struct:
+		// e.g. an element of an array of objects
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("cannot convert %T to %s", v, t)
+		}
+		item := NewConfig()
+		item.nestedMerge(m, "", false)
+		errs := &UnmarshalError{}
+		item.bindStruct("", rv, errs)
+		if len(errs.Missing) > 0 || len(errs.Invalid) > 0 {
+			return errors.New(strings.TrimPrefix(errs.Error(), "config: "))
+		}
+	default:
+		return fmt.Errorf("unsupported type %s", t)
+	}
+	return nil
+}
+
+func toBool(v interface{}) (bool, error) {
+	switch vv := v.(type) {
+	case bool:
+		return vv, nil
+	case string:
+		b, e := strconv.ParseBool(strings.TrimSpace(vv))
+		if e != nil {
+			return false, fmt.Errorf("cannot convert %q to bool", vv)
+		}
+		return b, nil
+	}
+	return false, fmt.Errorf("cannot convert %T to bool", v)
+}
+
+func toInt64(v interface{}) (int64, error) {
+	switch vv := v.(type) {
+	case float64:
+		if vv != math.Trunc(vv) {
+			return 0, fmt.Errorf("%g is not an integer", vv)
+		}
+		if vv < math.MinInt64 || vv >= math.MaxInt64 {
+			return 0, fmt.Errorf("%g overflows int64", vv)
+		}
+		return int64(vv), nil
+	case int:
+		return int64(vv), nil
+	case int64:
+		return vv, nil
+	case uint64:
+		if vv > math.MaxInt64 {
+			return 0, fmt.Errorf("%d overflows int64", vv)
+		}
+		return int64(vv), nil
+	case string:
+		i, e := strconv.ParseInt(strings.TrimSpace(vv), 0, 64)
+		if errors.Is(e, strconv.ErrRange) {
+			return 0, fmt.Errorf("%s overflows int64", strings.TrimSpace(vv))
+		}
+		if e != nil {
+			return 0, fmt.Errorf("cannot convert %q to an integer", vv)
+		}
+		return i, nil
+	}
+	return 0, fmt.Errorf("cannot convert %T to an integer", v)
+}
+
+func toUint64(v interface{}) (uint64, error) {
+	switch vv := v.(type) {
+	case uint64:
+		return vv, nil
+	case string:
+		u, e := strconv.ParseUint(strings.TrimSpace(vv), 0, 64)
+		if errors.Is(e, strconv.ErrRange) {
+			return 0, fmt.Errorf("%s overflows uint64", strings.TrimSpace(vv))
+		}
+		if e != nil {
+			return 0, fmt.Errorf("cannot convert %q to an unsigned integer", vv)
+		}
+		return u, nil
+	}
+	i, e := toInt64(v)
+	if e != nil {
+		return 0, e
+	}
+	if i < 0 {
+		return 0, fmt.Errorf("%d is negative", i)
+	}
+	return uint64(i), nil
+}
+
+func toFloat64(v interface{}) (float64, error) {
+	switch vv := v.(type) {
+	case float64:
+		return vv, nil
+	case int:
+		return float64(vv), nil
+	case int64:
+		return float64(vv), nil
+	case uint64:
+		return float64(vv), nil
+	case string:
+		f, e := strconv.ParseFloat(strings.TrimSpace(vv), 64)
+		if e != nil {
+			return 0, fmt.Errorf("cannot convert %q to a number", vv)
+		}
+		return f, nil
+	}
+	return 0, fmt.Errorf("cannot convert %T to a number", v)
+}
+
+// toDuration converts strings such as "30s" or "1h30m" to a time.Duration. Plain numbers
+// are taken to be seconds.
+func toDuration(v interface{}) (time.Duration, error) {
+	switch vv := v.(type) {
+	case time.Duration:
+		return vv, nil
+	case string:
+		s := strings.TrimSpace(vv)
+		if d, e := time.ParseDuration(s); e == nil {
+			return d, nil
+		}
+		if f, e := strconv.ParseFloat(s, 64); e == nil {
+			return time.Duration(f * float64(time.Second)), nil
+		}
+		return 0, fmt.Errorf("cannot convert %q to a duration", vv)
+	}
+	f, e := toFloat64(v)
+	if e != nil {
+		return 0, fmt.Errorf("cannot convert %T to a duration", v)
+	}
+	return time.Duration(f * float64(time.Second)), nil
+}
+
+// toTime converts RFC 3339 strings (or just a date) to a time.Time. Plain numbers are
+// taken to be seconds since the Unix epoch.
+func toTime(v interface{}) (time.Time, error) {
+	switch vv := v.(type) {
+	case time.Time:
+		return vv, nil
+	case string:
+		s := strings.TrimSpace(vv)
+		for _, layout := range timeLayouts {
+			if tm, e := time.Parse(layout, s); e == nil {
+				return tm, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("cannot convert %q to a time", vv)
+	}
+	f, e := toFloat64(v)
+	if e != nil {
+		return time.Time{}, fmt.Errorf("cannot convert %T to a time", v)
+	}
+	sec, frac := math.Modf(f)
+	return time.Unix(int64(sec), int64(frac*1e9)), nil
+}
+
+// toSlice converts arrays of any element type to []interface{}. A string is split on
+// commas, so "a, b" from the environment becomes ["a", "b"].
+func toSlice(v interface{}) ([]interface{}, error) {
+	switch vv := v.(type) {
+	case []interface{}:
+		return vv, nil
+	case string:
+		if strings.TrimSpace(vv) == "" {
+			return []interface{}{}, nil
+		}
+		parts := strings.Split(vv, ",")
+		result := make([]interface{}, len(parts))
+		for i, p := range parts {
+			result[i] = strings.TrimSpace(p)
+		}
+		return result, nil
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, fmt.Errorf("cannot convert %T to a list", v)
+	}
+	result := make([]interface{}, rv.Len())
+	for i := range result {
+		result[i] = rv.Index(i).Interface()
+	}
+	return result, nil
+}
+
+// sizeUnits are the multipliers of the units understood by toSize. Units with an "i" are
+// powers of 1024, the others powers of 1000.
+var sizeUnits = map[string]float64{
+	"":    1,
+	"b":   1,
+	"k":   1e3,
+	"kb":  1e3,
+	"ki":  1 << 10,
+	"kib": 1 << 10,
+	"m":   1e6,
+	"mb":  1e6,
+	"mi":  1 << 20,
+	"mib": 1 << 20,
+	"g":   1e9,
+	"gb":  1e9,
+	"gi":  1 << 30,
+	"gib": 1 << 30,
+	"t":   1e12,
+	"tb":  1e12,
+	"ti":  1 << 40,
+	"tib": 1 << 40,
+	"p":   1e15,
+	"pb":  1e15,
+	"pi":  1 << 50,
+	"pib": 1 << 50,
+}
+
+// toSize converts sizes such as "10MB" or "1.5GiB" to a number of bytes. Plain numbers
+// are taken to be bytes.
+func toSize(v interface{}) (int64, error) {
+	s, ok := v.(string)
+	if !ok {
+		return toInt64(v)
+	}
+
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i < 0 {
+		i = len(s)
+	}
+	n, e := strconv.ParseFloat(s[:i], 64)
+	if e != nil {
+		return 0, fmt.Errorf("cannot convert %q to a size", s)
+	}
+	unit, ok := sizeUnits[strings.ToLower(strings.TrimSpace(s[i:]))]
+	if !ok {
+		return 0, fmt.Errorf("cannot convert %q to a size: unknown unit", s)
+	}
+	size := n * unit
+	if size >= math.MaxInt64 {
+		return 0, fmt.Errorf("%q overflows int64", s)
+	}
+	return int64(size), nil
+}
+
+func toURL(v interface{}) (*url.URL, error) {
+	s, ok := v.(string)
+	if !ok {
+		return nil, fmt.Errorf("cannot convert %T to a URL", v)
+	}
+	if strings.TrimSpace(s) == "" {
+		return nil, fmt.Errorf("cannot convert an empty string to a URL")
+	}
+	return url.Parse(strings.TrimSpace(s))
+}
@@ -0,0 +1,136 @@
+package config
+
+import (
+	"encoding/json"
+	"html/template"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DebugInfo describes the state of a Config for operators, as served by DebugHandler. The
+// values of sensitive keys are redacted (see IsSensitive).
+type DebugInfo struct {
+	Keys []DebugKey `json:"keys"`
+	// LastReload is the time of the last reload, nil if the config has never been reloaded.
+	LastReload *time.Time `json:"lastReload,omitempty"`
+	// LastReloadError is the error the last reload failed with, empty if it succeeded.
+	LastReloadError string `json:"lastReloadError,omitempty"`
+}
+
+// DebugKey is the effective value of one key and where it came from.
+type DebugKey struct {
+	Key      string        `json:"key"`
+	Value    interface{}   `json:"value"`
+	Source   string        `json:"source"`
+	Shadowed []DebugSource `json:"shadowed,omitempty"`
+	Merged   []DebugSource `json:"merged,omitempty"`
+}
+
+// DebugSource is a value supplied by a source that was shadowed by another, or merged with
+// it (see SetArrayMerge).
+type DebugSource struct {
+	Source string      `json:"source"`
+	Value  interface{} `json:"value"`
+}
+
+// DebugInfo returns the redacted values of every key, their sources, and the result of the
+// last reload.
+func (c Config) DebugInfo() DebugInfo {
+	info := DebugInfo{Keys: []DebugKey{}}
+	for _, e := range c.ExplainAll() {
+		k := DebugKey{Key: e.Key, Value: c.redact(e.Key, e.Value)}
+		if e.Source != nil {
+			k.Source = e.Source.String()
+		}
+		for _, sh := range e.Shadowed {
+			k.Shadowed = append(k.Shadowed, DebugSource{Source: sh.String(), Value: c.redact(e.Key, sh.Value)})
+		}
+		for _, m := range e.Merged {
+			k.Merged = append(k.Merged, DebugSource{Source: m.String(), Value: c.redact(e.Key, m.Value)})
+		}
+		info.Keys = append(info.Keys, k)
+	}
+
+	t, e := c.LastReload()
+	if !t.IsZero() {
+		info.LastReload = &t
+	}
+	if e != nil {
+		info.LastReloadError = e.Error()
+	}
+	return info
+}
+
+var debugTemplate = template.Must(template.New("debug").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="UTF-8"><title>Configuration</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+td.shadowed { color: #888; }
+</style>
+</head>
+<body>
+<h1>Configuration</h1>
+<p>Last reload: {{if .LastReload}}{{.LastReload.Format "2006-01-02 15:04:05 MST"}}{{if .LastReloadError}} failed: {{.LastReloadError}}{{else}} succeeded{{end}}{{else}}never{{end}}</p>
+<table>
+<tr><th>Key</th><th>Value</th><th>Source</th><th>Shadowed</th></tr>
+{{range .Keys}}<tr><td>{{.Key}}</td><td>{{.Value}}</td><td>{{.Source}}{{range .Merged}}<br>merged with {{.Source}}{{end}}</td><td class="shadowed">{{range .Shadowed}}{{.Source}}: {{.Value}}<br>{{end}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// WriteDebugJSON writes info to w as indented JSON.
+func WriteDebugJSON(w io.Writer, info DebugInfo) error {
+	data, e := json.MarshalIndent(info, "", "  ")
+	if e != nil {
+		return e
+	}
+	_, e = w.Write(append(data, '\n'))
+	return e
+}
+
+// WriteDebugHTML writes info to w as an HTML page with a table of the keys.
+func WriteDebugHTML(w io.Writer, info DebugInfo) error {
+	return debugTemplate.Execute(w, info)
+}
+
+// WantsDebugHTML reports whether a request for the debug page asked for HTML, with
+// "?format=html" or an Accept header preferring text/html (as browsers send). Otherwise
+// JSON is served.
+func WantsDebugHTML(format string, accept string) bool {
+	if format != "" {
+		return format == "html"
+	}
+	return strings.HasPrefix(accept, "text/html")
+}
+
+// DebugHandler returns an http.Handler that serves DebugInfo as JSON, or as an HTML table
+// if the client asks for it (see WantsDebugHTML). The configuration is redacted, but still
+// reveals a lot about a deployment, so mount the handler on an internal port or behind an
+// access check, e.g.
+//
+//	http.Handle("/debug/config", requireAdmin(conf.DebugHandler()))
+func (c Config) DebugHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Cache-Control", "no-store")
+		info := c.DebugInfo()
+		if WantsDebugHTML(r.URL.Query().Get("format"), r.Header.Get("Accept")) {
+			w.Header().Set("Content-Type", "text/html; charset=UTF-8")
+			WriteDebugHTML(w, info)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		WriteDebugJSON(w, info)
+	})
+}
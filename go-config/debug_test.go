@@ -0,0 +1,67 @@
+package config
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDebugHandler(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.json")
+	os.WriteFile(path, []byte(`{"db": {"host": "db1", "password": "hunter2"}}`), 0644)
+
+	conf := NewConfig()
+	conf.AddDefault("db.host", "localhost")
+	conf.AddFile(path, "", true)
+	h := conf.DebugHandler()
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/debug/config", nil))
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "application/json") {
+		t.Fatalf("Expected JSON, got %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if strings.Contains(rec.Body.String(), "hunter2") {
+		t.Errorf("Expected the password to be redacted, got %s", rec.Body.String())
+	}
+
+	var info DebugInfo
+	if e := json.Unmarshal(rec.Body.Bytes(), &info); e != nil {
+		t.Fatal(e)
+	}
+	if len(info.Keys) != 2 || info.LastReload != nil {
+		t.Fatalf("Expected 2 keys and no reload, got %+v", info)
+	}
+	host := info.Keys[0]
+	if host.Key != "db.host" || host.Value != "db1" || host.Source != "file "+path ||
+		len(host.Shadowed) != 1 || host.Shadowed[0].Value != "localhost" {
+		t.Errorf("Expected db.host from the file, shadowing the default, got %+v", host)
+	}
+
+	os.Remove(path)
+	conf.Reload()
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/debug/config?format=html", nil))
+	body := rec.Body.String()
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html") || !strings.Contains(body, "<td>db.host</td>") ||
+		!strings.Contains(body, "failed: ") || strings.Contains(body, "hunter2") {
+		t.Errorf("Expected an HTML table with the failed reload, got %s", body)
+	}
+
+	req := httptest.NewRequest("GET", "/debug/config", nil)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html") {
+		t.Errorf("Expected HTML for a browser, got %s", rec.Header().Get("Content-Type"))
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("POST", "/debug/config", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected POST to be refused, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,128 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+// Encode writes the config to w as a nested document in the given format: FormatJSON,
+// FormatYAML or FormatTOML. Dot-delimited keys are turned back into nested objects (see
+// AsNestedMap) and written in sorted order, so encoding the same config always gives the
+// same output. Only the values of files and defaults are written: those from the
+// environment, flags, secrets directories and other providers would otherwise end up
+// persisted, perhaps in plain text, in a file readable by anyone. Values are written as they
+// were merged, so references such as "${other.key}" and secret references are kept rather
+// than expanded. Numbers that are whole are written as integers.
+func (c Config) Encode(w io.Writer, format string) error {
+	nested := encodable(c.persistable().nestedMap(false)).(map[string]interface{})
+
+	var data []byte
+	var e error
+	switch strings.ToLower(format) {
+	case FormatJSON:
+		data, e = json.MarshalIndent(nested, "", "  ")
+		data = append(data, '\n')
+	case FormatYAML, "yml":
+		data, e = yaml.Marshal(nested)
+	case FormatTOML:
+		var b bytes.Buffer
+		e = toml.NewEncoder(&b).Encode(nested)
+		data = b.Bytes()
+	default:
+		return fmt.Errorf("config: cannot encode format %q", format)
+	}
+	if e != nil {
+		return e
+	}
+	_, e = w.Write(data)
+	return e
+}
+
+// Save writes the config to path with Encode, in the format implied by its extension (see
+// FormatForPath). The file is replaced atomically: the config is written to a temporary
+// file in the same directory, which is then renamed over path, so readers (including a
+// Watcher) never see a partly written file. An existing file keeps its permissions; a new
+// one is readable only by its owner, as configs often hold credentials.
+func (c Config) Save(path string) error {
+	var b bytes.Buffer
+	if e := c.Encode(&b, FormatForPath(path)); e != nil {
+		return e
+	}
+
+	mode := os.FileMode(0600)
+	if info, e := os.Stat(path); e == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmp, e := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if e != nil {
+		return e
+	}
+	// remove the temporary file if anything fails; after the rename this does nothing
+	defer os.Remove(tmp.Name())
+
+	if _, e := tmp.Write(b.Bytes()); e != nil {
+		tmp.Close()
+		return e
+	}
+	if e := tmp.Sync(); e != nil {
+		tmp.Close()
+		return e
+	}
+	if e := tmp.Close(); e != nil {
+		return e
+	}
+	if e := os.Chmod(tmp.Name(), mode); e != nil {
+		return e
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// persistable returns a config with the values of only the files and defaults merged into
+// c, for Encode.
+func (c Config) persistable() Config {
+	c.s.mu.RLock()
+	defer c.s.mu.RUnlock()
+
+	values := make(map[string]interface{})
+	for _, l := range c.s.layers {
+		if l.kind == SourceFile || l.kind == SourceDefault {
+			l.apply(values, c.s.arrayMerges)
+		}
+	}
+	return Config{s: newStore(values), prefix: c.prefix}
+}
+
+// encodable returns a copy of v with whole numbers as int64, so they are written as
+// integers rather than, say, 8080.0 in TOML.
+func encodable(v interface{}) interface{} {
+	switch vv := v.(type) {
+	case float64:
+		if vv == math.Trunc(vv) && math.Abs(vv) < 1<<53 {
+			return int64(vv)
+		}
+	case []interface{}:
+		result := make([]interface{}, len(vv))
+		for i, item := range vv {
+			result[i] = encodable(item)
+		}
+		return result
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(vv))
+		for k, item := range vv {
+			result[k] = encodable(item)
+		}
+		return result
+	}
+	return v
+}
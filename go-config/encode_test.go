@@ -0,0 +1,136 @@
+package config
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEncode(t *testing.T) {
+	conf, _ := ReadFromFile("./test.json")
+	conf.AddDefaultOverride("test1.child1.child1prop", "${test1.strprop}")
+
+	for _, format := range []string{FormatJSON, FormatYAML, FormatTOML} {
+		var b bytes.Buffer
+		if e := conf.Encode(&b, format); e != nil {
+			t.Fatalf("%s: %v", format, e)
+		}
+
+		// the output is stable
+		var again bytes.Buffer
+		conf.Encode(&again, format)
+		if b.String() != again.String() {
+			t.Errorf("%s: Expected the same output twice, got:\n%s\n%s", format, b.String(), again.String())
+		}
+		if !strings.Contains(b.String(), "${test1.strprop}") {
+			t.Errorf("%s: Expected references to be kept, got:\n%s", format, b.String())
+		}
+
+		// and reads back as the same config
+		nested, e := decode(b.Bytes(), format)
+		if e != nil {
+			t.Fatalf("%s: %v\n%s", format, e, b.String())
+		}
+		read := NewConfig()
+		read.nestedMerge(nested, "", false)
+		if !reflect.DeepEqual(read.snapshot(), conf.snapshot()) {
+			t.Errorf("%s: Expected %v, got %v", format, conf.snapshot(), read.snapshot())
+		}
+	}
+
+	var b bytes.Buffer
+	conf.Encode(&b, FormatTOML)
+	if !strings.Contains(b.String(), "child1prop = \"${test1.strprop}\"") || strings.Contains(b.String(), ".0") {
+		t.Errorf("Expected whole numbers to be written as integers, got:\n%s", b.String())
+	}
+
+	if e := conf.Encode(&b, FormatINI); e == nil {
+		t.Errorf("Expected an error encoding INI")
+	}
+}
+
+func TestSave(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.yaml")
+	ioutil.WriteFile(path, []byte("db:\n  host: old\n"), 0640)
+
+	conf := NewConfig()
+	if e := conf.AddFile(path, "", false); e != nil {
+		t.Fatal(e)
+	}
+	conf.AddDefaultOverride("db.host", "new")
+	conf.AddDefaultInt("db.port", 5432)
+	if e := conf.Save(path); e != nil {
+		t.Fatal(e)
+	}
+
+	data, _ := ioutil.ReadFile(path)
+	if string(data) != "db:\n  host: new\n  port: 5432\n" {
+		t.Errorf("Expected the new settings as YAML, got:\n%s", data)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0640 {
+		t.Errorf("Expected the file to keep its permissions, got %v", info.Mode())
+	}
+	if entries, _ := ioutil.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("Expected no temporary files to be left, got %d files", len(entries))
+	}
+
+	if e := conf.Save(filepath.Join(dir, "app.ini")); e == nil {
+		t.Errorf("Expected an error saving as INI")
+	}
+	if entries, _ := ioutil.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("Expected nothing to be written for a failed save, got %d files", len(entries))
+	}
+
+	// a new file is readable only by its owner
+	created := filepath.Join(dir, "new.json")
+	if e := conf.Save(created); e != nil {
+		t.Fatal(e)
+	}
+	if info, _ := os.Stat(created); info.Mode().Perm() != 0600 {
+		t.Errorf("Expected a new file to be created with mode 0600, got %v", info.Mode())
+	}
+}
+
+func TestEncodeOnlyFilesAndDefaults(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"app.json":            `{"db": {"host": "file.example"}}`,
+		"secrets/db_password": "hunter2",
+	})
+	os.Setenv("ENCODETEST_USER", "admin")
+	defer os.Unsetenv("ENCODETEST_USER")
+
+	conf := NewConfig()
+	if e := conf.AddFile(filepath.Join(dir, "app.json"), "", false); e != nil {
+		t.Fatal(e)
+	}
+	conf.AddDefaultInt("db.port", 5432)
+	conf.AddEnvironment("ENCODETEST_", "", true)
+	if e := conf.AddSecretsDir(filepath.Join(dir, "secrets"), ""); e != nil {
+		t.Fatal(e)
+	}
+	if conf.GetString("ENCODETEST_USER") != "admin" || conf.GetString("db_password") != "hunter2" {
+		t.Fatalf("Expected the environment and secrets to be read, got %v", conf.AsNestedMap())
+	}
+
+	var b bytes.Buffer
+	if e := conf.Encode(&b, FormatJSON); e != nil {
+		t.Fatal(e)
+	}
+	expected := "{\n  \"db\": {\n    \"host\": \"file.example\",\n    \"port\": 5432\n  }\n}\n"
+	if b.String() != expected {
+		t.Errorf("Expected only the file and defaults to be encoded, got:\n%s", b.String())
+	}
+
+	// a view encodes its own section
+	b.Reset()
+	conf.Sub("db").Encode(&b, FormatYAML)
+	if b.String() != "host: file.example\nport: 5432\n" {
+		t.Errorf("Expected the db section, got:\n%s", b.String())
+	}
+}
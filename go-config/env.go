@@ -0,0 +1,144 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+)
+
+// EnvOptions controls how AddEnvironmentMapped turns environment variables into keys.
+type EnvOptions struct {
+	// Prefix selects the variables to read, e.g. "APP_". It is stripped from their names.
+	Prefix string
+	// Separator splits the rest of a name into key segments. It defaults to "_", so
+	// APP_DB_HOST becomes db.host. Use "__" to keep single underscores within a segment.
+	Separator string
+	// DestPrefix places the keys within a namespace of the Config, as for AddEnvironment.
+	DestPrefix string
+	// Override determines whether the variables override existing settings.
+	Override bool
+	// InferTypes converts values that look like numbers or booleans to numbers and bool,
+	// as they would be if read from JSON, and decodes values that are JSON arrays or objects.
+	InferTypes bool
+	// ListSeparator, if not empty, splits values containing it into a list, so with ","
+	// the value "a,b" becomes ["a", "b"]. Elements are type-inferred if InferTypes is set.
+	ListSeparator string
+}
+
+// AddEnvironmentMapped adds configuration properties from environment variables whose names
+// start with opts.Prefix, mapping each name to a nested key: the prefix is stripped, the rest
+// is split on opts.Separator, lowercased and joined with ".". If a key already exists with
+// different case (e.g. "db.maxConns" from a JSON file) that spelling is used, so environment
+// variables line up with, and can override, settings from files. The variables are read
+// with an EnvProvider, so Reload reads them again.
+func (c Config) AddEnvironmentMapped(opts EnvOptions) {
+	// values are already flattened; they are merged in as they are, so that an object
+	// decoded from JSON is flattened beneath its key
+	l, e := c.addProvider(EnvProvider{Options: opts, existing: c.foldedKeys()}, "", opts.Override)
+	if e != nil {
+		c.debugf("config: AddEnvironmentMapped(%q): %s", opts.Prefix, e)
+		return
+	}
+	c.debugf("config: AddEnvironmentMapped(%q): setting %s", opts.Prefix, sortedKeys(l.origins))
+}
+
+// mapEnvironment returns the values of the variables selected by opts, keyed as described
+// for AddEnvironmentMapped, and the variable each key came from. Keys are matched
+// case-insensitively against existing, which maps lowercased keys to their spelling.
+func mapEnvironment(opts EnvOptions, existing map[string]string) (map[string]interface{}, map[string]string) {
+	if opts.Separator == "" {
+		opts.Separator = "_"
+	}
+
+	values := make(map[string]interface{})
+	origins := make(map[string]string)
+	for _, x := range os.Environ() {
+		kv := strings.SplitN(x, "=", 2)
+		if len(kv) != 2 || !strings.HasPrefix(kv[0], opts.Prefix) {
+			continue
+		}
+
+		key := envKey(strings.TrimPrefix(kv[0], opts.Prefix), opts.Separator)
+		if key == "" {
+			continue
+		}
+		key = joinKey(opts.DestPrefix, key)
+		if k, ok := existing[key]; ok {
+			key = k
+		}
+
+		values[key] = envValue(kv[1], opts)
+		origins[key] = kv[0]
+	}
+	return values, origins
+}
+
+// foldedKeys maps the lowercased form of every key to the key itself, so that names from
+// case-insensitive sources can be matched to existing keys.
+func (c Config) foldedKeys() map[string]string {
+	existing := make(map[string]string)
+	for _, k := range c.Keys() {
+		existing[strings.ToLower(k)] = k
+	}
+	return existing
+}
+
+// envKey converts the name of a variable (without its prefix) to a dot-delimited key.
+func envKey(name string, separator string) string {
+	var segments []string
+	for _, s := range strings.Split(name, separator) {
+		if s != "" {
+			segments = append(segments, strings.ToLower(s))
+		}
+	}
+	return strings.Join(segments, ".")
+}
+
+// envValue converts the value of a variable according to opts.
+func envValue(v string, opts EnvOptions) interface{} {
+	if opts.InferTypes {
+		trimmed := strings.TrimSpace(v)
+		if strings.HasPrefix(trimmed, "[") || strings.HasPrefix(trimmed, "{") {
+			var decoded interface{}
+			dec := json.NewDecoder(strings.NewReader(trimmed))
+			dec.UseNumber()
+			if json.Valid([]byte(trimmed)) && dec.Decode(&decoded) == nil {
+				return normalise(decoded)
+			}
+		}
+	}
+
+	if opts.ListSeparator != "" && strings.Contains(v, opts.ListSeparator) {
+		parts := strings.Split(v, opts.ListSeparator)
+		list := make([]interface{}, len(parts))
+		for i, p := range parts {
+			p = strings.TrimSpace(p)
+			if opts.InferTypes {
+				list[i] = inferScalar(p)
+			} else {
+				list[i] = p
+			}
+		}
+		return list
+	}
+
+	if opts.InferTypes {
+		return inferScalar(v)
+	}
+	return v
+}
+
+// inferScalar converts v to a bool or number (see parseNumber) if it looks like one.
+func inferScalar(v string) interface{} {
+	s := strings.TrimSpace(v)
+	switch s {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	if n, e := parseNumber(s); e == nil && s != "" && !strings.ContainsAny(s, "xXnN_") {
+		return n
+	}
+	return v
+}
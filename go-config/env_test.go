@@ -0,0 +1,59 @@
+package config
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestEnvironmentMapped(t *testing.T) {
+	env := map[string]string{
+		"ENVTEST_TEST1_STRPROP":           "from env",
+		"ENVTEST_TEST1_CHILD2_MENUTTL":    "60",
+		"ENVTEST_TEST1_BOOLPROP":          "false",
+		"ENVTEST_TEST1_ARRAYPROP":         `["x", 1]`,
+		"ENVTEST_TEST1_HOSTS":             "a.example.com, b.example.com",
+		"ENVTEST_TEST1_LIMITS":            `{"max": 10}`,
+		"ENVTEST_DB__MAX_CONNS":           "5",
+		"ENVTEST_TEST1_CHILD1_CHILD1PROP": "0x10",
+	}
+	for k, v := range env {
+		os.Setenv(k, v)
+		defer os.Unsetenv(k)
+	}
+
+	conf, _ := ReadFromFile("./test.json")
+	conf.AddEnvironmentMapped(EnvOptions{Prefix: "ENVTEST_", Override: true, InferTypes: true, ListSeparator: ","})
+
+	if conf.GetString("test1.strprop") != "from env" {
+		t.Errorf("Expected test1.strprop to be overridden from the environment, got '%v'", conf.Get("test1.strprop"))
+	}
+	// the camel case key from the file is matched
+	if conf.Get("test1.child2.menuTTL") != 60.0 {
+		t.Errorf("Expected test1.child2.menuTTL to be overridden with 60, got '%#v'", conf.Get("test1.child2.menuTTL"))
+	}
+	if conf.Get("test1.boolprop") != false {
+		t.Errorf("Expected test1.boolprop to be overridden with false, got '%#v'", conf.Get("test1.boolprop"))
+	}
+	if !reflect.DeepEqual(conf.Get("test1.arrayprop"), []interface{}{"x", 1.0}) {
+		t.Errorf("Expected test1.arrayprop to be decoded from JSON, got '%#v'", conf.Get("test1.arrayprop"))
+	}
+	if !reflect.DeepEqual(conf.Get("test1.hosts"), []interface{}{"a.example.com", "b.example.com"}) {
+		t.Errorf("Expected test1.hosts to be split into a list, got '%#v'", conf.Get("test1.hosts"))
+	}
+	if conf.Get("test1.limits.max") != 10.0 {
+		t.Errorf("Expected test1.limits.max to be flattened from a JSON object, got '%#v'", conf.Get("test1.limits.max"))
+	}
+	if conf.Get("test1.child1.child1prop") != "0x10" {
+		t.Errorf("Expected test1.child1.child1prop to be left as a string, got '%#v'", conf.Get("test1.child1.child1prop"))
+	}
+	if conf.Explain("test1.strprop").Source.Name != "ENVTEST_TEST1_STRPROP" {
+		t.Errorf("Expected test1.strprop to be explained by ENVTEST_TEST1_STRPROP, got %s", conf.Explain("test1.strprop"))
+	}
+
+	conf = NewConfig()
+	conf.AddEnvironmentMapped(EnvOptions{Prefix: "ENVTEST_", Separator: "__", DestPrefix: "env"})
+	if conf.Get("env.db.max_conns") != "5" {
+		t.Errorf("Expected env.db.max_conns to be \"5\" with a __ separator, got '%#v'", conf.Get("env.db.max_conns"))
+	}
+}
@@ -0,0 +1,142 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// Source describes one source that supplied a value for a key.
+type Source struct {
+	// Kind is one of the Source* constants.
+	Kind string
+	// Name identifies the source: the path of a file, or the name of an environment
+	// variable. It is empty for defaults.
+	Name string
+	// Override is the override setting the source was added with.
+	Override bool
+	// Value is the value this source supplied.
+	Value interface{}
+}
+
+func (s Source) String() string {
+	if s.Name == "" {
+		return s.Kind
+	}
+	return s.Kind + " " + s.Name
+}
+
+// Explanation tells why a key has the value it has.
+type Explanation struct {
+	Key string
+	// Value is the effective value of the key, nil if it isn't set.
+	Value interface{}
+	// Source is the source that supplied Value, nil if the key isn't set.
+	Source *Source
+	// Shadowed lists the other sources that supplied the key, in the order they were
+	// added, whose values lost to Source.
+	Shadowed []Source
+	// Merged lists the other sources whose arrays were combined with that of Source to
+	// give Value, according to the strategy set with SetArrayMerge.
+	Merged []Source
+}
+
+func (e Explanation) String() string {
+	if e.Source == nil {
+		return e.Key + " is not set"
+	}
+	s := fmt.Sprintf("%s = %v (from %s)", e.Key, e.Value, e.Source)
+	for _, m := range e.Merged {
+		s += fmt.Sprintf("\n  merges %v from %s", m.Value, m)
+	}
+	for _, sh := range e.Shadowed {
+		s += fmt.Sprintf("\n  shadows %v from %s", sh.Value, sh)
+	}
+	return s
+}
+
+// Explain reports which source supplied the value of key, and which other sources
+// supplied values that were shadowed by it, either because they were added earlier and
+// overridden, or were added later without override.
+func (c Config) Explain(key string) Explanation {
+	c.s.mu.RLock()
+	defer c.s.mu.RUnlock()
+	e := c.s.explain(c.full(key))
+	e.Key = key
+	return e
+}
+
+func (s *store) explain(key string) Explanation {
+	e := Explanation{Key: key}
+	for _, l := range flatLayers(s.layers) {
+		v, ok := l.values[key]
+		if !ok {
+			continue
+		}
+		src := Source{Kind: l.kind, Name: l.name, Override: l.override, Value: v}
+		if origin, ok := l.origins[key]; ok {
+			src.Name = origin
+		}
+
+		if e.Source == nil {
+			e.Source = &src
+			e.Value = v
+			continue
+		}
+		lower, higher := e.Value, v
+		if !l.override {
+			lower, higher = v, e.Value
+		}
+		if merged, ok := s.arrayMerges.merge(key, lower, higher); ok {
+			e.Value = merged
+			if l.override {
+				e.Merged = append(e.Merged, *e.Source)
+				e.Source = &src
+			} else {
+				e.Merged = append(e.Merged, src)
+			}
+		} else if l.override {
+			e.Shadowed = append(e.Shadowed, *e.Source)
+			e.Source = &src
+			e.Value = v
+		} else {
+			e.Shadowed = append(e.Shadowed, src)
+		}
+	}
+	return e
+}
+
+// ExplainAll returns the explanation of every key in the config, sorted by key.
+func (c Config) ExplainAll() []Explanation {
+	keys := c.Keys()
+
+	c.s.mu.RLock()
+	defer c.s.mu.RUnlock()
+	result := make([]Explanation, len(keys))
+	for i, k := range keys {
+		result[i] = c.s.explain(c.full(k))
+		result[i].Key = k
+	}
+	return result
+}
+
+// DumpSources writes a table of every key, its value, the source it came from (and any
+// whose arrays were merged with it) and any shadowed sources to w, for troubleshooting.
+// The values of sensitive keys (see IsSensitive) are redacted.
+func (c Config) DumpSources(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "KEY\tVALUE\tSOURCE\tSHADOWED")
+	for _, e := range c.ExplainAll() {
+		shadowed := make([]string, len(e.Shadowed))
+		for i, sh := range e.Shadowed {
+			shadowed[i] = fmt.Sprintf("%s=%v", sh, c.redact(e.Key, sh.Value))
+		}
+		source := fmt.Sprint(e.Source)
+		for _, m := range e.Merged {
+			source += " + " + m.String()
+		}
+		fmt.Fprintf(tw, "%s\t%v\t%s\t%s\n", e.Key, c.redact(e.Key, e.Value), source, strings.Join(shadowed, ", "))
+	}
+	return tw.Flush()
+}
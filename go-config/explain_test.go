@@ -0,0 +1,46 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	os.Setenv("EXPLAINTEST_PORT", "9000")
+	defer os.Unsetenv("EXPLAINTEST_PORT")
+
+	conf := NewConfig()
+	conf.AddDefaultInt("EXPLAINTEST_PORT", 80)
+	conf.AddFile("./test.json", "", false)
+	conf.AddEnvironment("EXPLAINTEST_", "", true)
+	conf.AddDefault("test1.strprop", "ignored")
+
+	e := conf.Explain("EXPLAINTEST_PORT")
+	if e.Value != "9000" || e.Source == nil || e.Source.Kind != SourceEnv || e.Source.Name != "EXPLAINTEST_PORT" {
+		t.Errorf("Expected EXPLAINTEST_PORT to come from the environment, got %s", e)
+	}
+	if len(e.Shadowed) != 1 || e.Shadowed[0].Kind != SourceDefault || e.Shadowed[0].Value != 80 {
+		t.Errorf("Expected EXPLAINTEST_PORT to shadow the default 80, got %v", e.Shadowed)
+	}
+
+	e = conf.Explain("test1.strprop")
+	if e.Source == nil || e.Source.Kind != SourceFile || e.Source.Name != "./test.json" {
+		t.Errorf("Expected test1.strprop to come from ./test.json, got %s", e)
+	}
+	if len(e.Shadowed) != 1 || e.Shadowed[0].Value != "ignored" {
+		t.Errorf("Expected test1.strprop to shadow the later default, got %v", e.Shadowed)
+	}
+
+	e = conf.Explain("non-existent")
+	if e.Source != nil || e.Value != nil {
+		t.Errorf("Expected non-existent to have no source, got %s", e)
+	}
+
+	var b bytes.Buffer
+	conf.DumpSources(&b)
+	if !strings.Contains(b.String(), "test1.strprop") || !strings.Contains(b.String(), "env EXPLAINTEST_PORT") {
+		t.Errorf("Expected DumpSources to list test1.strprop and its sources, got:\n%s", b.String())
+	}
+}
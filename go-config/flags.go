@@ -0,0 +1,140 @@
+package config
+
+import (
+	"flag"
+	"reflect"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// FlagOptions controls how AddFlags and AddPFlags turn flags into keys.
+type FlagOptions struct {
+	// DestPrefix places the keys within a namespace of the Config, as for AddEnvironment.
+	DestPrefix string
+	// Separator, if not empty, is replaced by "." in flag names, so with "-" the flag
+	// --db-host sets db.host. Flag names are otherwise used as keys as they are, so
+	// --db.host sets db.host.
+	Separator string
+}
+
+// AddFlags adds the flags of fs (flag.CommandLine if nil) that were set on the command
+// line. fs must already have been parsed. Flags that were not set are ignored, so their
+// defaults don't replace settings from other sources. Flags always override existing
+// settings, so adding them last gives the usual precedence of defaults < files <
+// environment < flags. Typed flags keep their type, with numbers stored as float64 as if
+// read from JSON; names are matched case-insensitively to existing keys. The flags are
+// read with a FlagProvider.
+func (c Config) AddFlags(fs *flag.FlagSet, opts FlagOptions) {
+	if _, e := c.addProvider(FlagProvider{FlagSet: fs, Options: opts, existing: c.foldedKeys()}, "", true); e != nil {
+		c.debugf("config: AddFlags: %s", e)
+	}
+}
+
+// visitFlags returns the flags of fs (flag.CommandLine if nil) that were set.
+func visitFlags(fs *flag.FlagSet) []setFlag {
+	if fs == nil {
+		fs = flag.CommandLine
+	}
+
+	var set []setFlag
+	fs.Visit(func(f *flag.Flag) {
+		var v interface{} = f.Value.String()
+		if g, ok := f.Value.(flag.Getter); ok {
+			v = flagValue(g.Get())
+		}
+		set = append(set, setFlag{f.Name, v})
+	})
+	return set
+}
+
+// AddPFlags is like AddFlags, but for a flag set from github.com/spf13/pflag. The flags are
+// read with a PFlagProvider.
+func (c Config) AddPFlags(fs *pflag.FlagSet, opts FlagOptions) {
+	if _, e := c.addProvider(PFlagProvider{FlagSet: fs, Options: opts, existing: c.foldedKeys()}, "", true); e != nil {
+		c.debugf("config: AddPFlags: %s", e)
+	}
+}
+
+// visitPFlags returns the flags of fs (pflag.CommandLine if nil) that were set.
+func visitPFlags(fs *pflag.FlagSet) []setFlag {
+	if fs == nil {
+		fs = pflag.CommandLine
+	}
+
+	var set []setFlag
+	fs.Visit(func(f *pflag.Flag) {
+		set = append(set, setFlag{f.Name, pflagValue(f.Value)})
+	})
+	return set
+}
+
+// setFlag is a flag that was set on the command line, from either flag package.
+type setFlag struct {
+	name  string
+	value interface{}
+}
+
+// flagValues returns the values of the set flags keyed as described for AddFlags, and
+// the flag each key came from. Keys are matched case-insensitively against existing.
+func flagValues(set []setFlag, opts FlagOptions, existing map[string]string) (map[string]interface{}, map[string]string) {
+	values := make(map[string]interface{})
+	origins := make(map[string]string)
+	for _, f := range set {
+		key := f.name
+		if opts.Separator != "" {
+			key = strings.Replace(key, opts.Separator, ".", -1)
+		}
+		key = joinKey(opts.DestPrefix, key)
+		if k, ok := existing[strings.ToLower(key)]; ok {
+			key = k
+		}
+		values[key] = f.value
+		origins[key] = "--" + f.name
+	}
+	return values, origins
+}
+
+// flagValue converts numbers from typed flags to float64 (or, for large integers, int64 or
+// uint64), matching values read from JSON.
+func flagValue(v interface{}) interface{} {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if _, ok := v.(interface{ String() string }); ok {
+			// e.g. time.Duration, which is kept as it is
+			return v
+		}
+		return intNumber(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return uintNumber(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		return rv.Float()
+	}
+	return v
+}
+
+// pflagValue converts a pflag value using its type name, as pflag values don't implement
+// flag.Getter.
+func pflagValue(v pflag.Value) interface{} {
+	if sv, ok := v.(pflag.SliceValue); ok {
+		items := sv.GetSlice()
+		list := make([]interface{}, len(items))
+		for i, item := range items {
+			list[i] = item
+		}
+		return list
+	}
+
+	s := v.String()
+	t := v.Type()
+	switch {
+	case t == "bool":
+		return s == "true"
+	case t == "duration" || t == "string":
+		return s
+	case strings.HasPrefix(t, "int") || strings.HasPrefix(t, "uint") || strings.HasPrefix(t, "float"):
+		return inferScalar(s)
+	}
+	return s
+}
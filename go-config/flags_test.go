@@ -0,0 +1,67 @@
+package config
+
+import (
+	"flag"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+func TestFlags(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.String("test1.strprop", "default", "")
+	fs.Int("test1.intprop", 1, "")
+	fs.Duration("test1.child2.menuttl", time.Second, "")
+	fs.Bool("test1.boolprop", true, "")
+	fs.Parse([]string{"--test1.strprop=from flag", "--test1.child2.menuttl", "1m"})
+
+	conf, _ := ReadFromFile("./test.json")
+	conf.AddFlags(fs, FlagOptions{})
+
+	if conf.GetString("test1.strprop") != "from flag" {
+		t.Errorf("Expected test1.strprop to be set from the flag, got '%v'", conf.Get("test1.strprop"))
+	}
+	// flags not set on the command line don't replace settings with their defaults
+	if conf.Get("test1.intprop") != 123.0 || conf.Get("test1.boolprop") != true {
+		t.Errorf("Expected unset flags to be ignored, got test1.intprop '%v' and test1.boolprop '%v'", conf.Get("test1.intprop"), conf.Get("test1.boolprop"))
+	}
+	// the camel case key from the file is matched
+	if conf.Get("test1.child2.menuTTL") != time.Minute {
+		t.Errorf("Expected test1.child2.menuTTL to be set from the flag, got '%#v'", conf.Get("test1.child2.menuTTL"))
+	}
+	if e := conf.Explain("test1.strprop"); e.Source.Kind != SourceFlag || e.Source.Name != "--test1.strprop" {
+		t.Errorf("Expected test1.strprop to be explained by --test1.strprop, got %s", e)
+	}
+}
+
+func TestPFlags(t *testing.T) {
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	fs.String("db-host", "localhost", "")
+	fs.Int("db-port", 5432, "")
+	fs.StringSlice("db-replicas", nil, "")
+	fs.Parse([]string{"--db-port=6432", "--db-replicas=a,b"})
+
+	conf := NewConfig()
+	conf.AddPFlags(fs, FlagOptions{Separator: "-", DestPrefix: "app"})
+
+	if conf.Get("app.db.port") != 6432.0 {
+		t.Errorf("Expected app.db.port to be 6432, got '%#v'", conf.Get("app.db.port"))
+	}
+	if !reflect.DeepEqual(conf.Get("app.db.replicas"), []interface{}{"a", "b"}) {
+		t.Errorf("Expected app.db.replicas to be [a b], got '%#v'", conf.Get("app.db.replicas"))
+	}
+	if conf.HasKey("app.db.host") {
+		t.Errorf("Expected app.db.host not to be set, got '%v'", conf.Get("app.db.host"))
+	}
+
+	// the flags are a provider, read again by Reload
+	if e := conf.Explain("app.db.port"); e.Source == nil || e.Source.Kind != SourceFlag || e.Source.Name != "--db-port" {
+		t.Errorf("Expected app.db.port from --db-port, got %s", e)
+	}
+	fs.Set("db-host", "db.example.com")
+	if changed, e := conf.Reload(); e != nil || !reflect.DeepEqual(changed, []string{"app.db.host"}) {
+		t.Errorf("Expected app.db.host to be added, got %v, %v", changed, e)
+	}
+}
@@ -0,0 +1,470 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+// File formats understood by AddFileWithFormat.
+const (
+	FormatJSON       = "json"
+	FormatYAML       = "yaml"
+	FormatTOML       = "toml"
+	FormatINI        = "ini"
+	FormatProperties = "properties"
+	// FormatJSONC is JSON that may also contain // and /* */ comments and trailing commas
+	// in objects and arrays, as written by many editors. It is not JSON5: unquoted keys,
+	// single-quoted strings and the like are still errors.
+	FormatJSONC = "jsonc"
+)
+
+// FormatForPath returns the file format implied by the extension of path. Unknown
+// extensions are treated as JSON, which was the only format supported originally.
+func FormatForPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jsonc":
+		return FormatJSONC
+	case ".yaml", ".yml":
+		return FormatYAML
+	case ".toml":
+		return FormatTOML
+	case ".ini", ".cfg":
+		return FormatINI
+	case ".properties":
+		return FormatProperties
+	}
+	return FormatJSON
+}
+
+// decode parses data in the given format into a nested map, ready for nestedMerge. Errors
+// are all *ParseError, so that the caller need only set File.
+func decode(data []byte, format string) (map[string]interface{}, error) {
+	nested, e := decodeFormat(data, format)
+	if e != nil {
+		if _, ok := e.(*ParseError); !ok {
+			e = &ParseError{Err: e}
+		}
+		return nil, e
+	}
+	return nested, nil
+}
+
+func decodeFormat(data []byte, format string) (map[string]interface{}, error) {
+	switch strings.ToLower(format) {
+	case FormatJSON:
+		return decodeJSON(data)
+	case FormatJSONC:
+		return decodeJSON(stripJSONC(data))
+	case FormatYAML, "yml":
+		return decodeYAML(data)
+	case FormatTOML:
+		return decodeTOML(data)
+	case FormatINI:
+		return decodeINI(data)
+	case FormatProperties:
+		return decodeProperties(data)
+	}
+	return nil, fmt.Errorf("unknown file format %q", format)
+}
+
+// ParseError reports a file that could not be parsed, and where.
+type ParseError struct {
+	// File is the path of the file, or the URL it was fetched from; it may be empty.
+	File string
+	// Line and Column locate the error, counting from 1. They are 0 if unknown.
+	Line   int
+	Column int
+	Err    error
+}
+
+func (e *ParseError) Error() string {
+	where := e.File
+	if e.Line > 0 {
+		where = fmt.Sprintf("%s:%d:%d", where, e.Line, e.Column)
+	}
+	where = strings.TrimPrefix(where, ":")
+	if where == "" {
+		return "config: " + e.Err.Error()
+	}
+	return fmt.Sprintf("config: %s: %s", where, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+func decodeJSON(data []byte) (map[string]interface{}, error) {
+	// Unmarshal checks the syntax, reporting errors with their offsets, and the Decoder then
+	// reads numbers as json.Number, so that normalise can keep them without losing precision.
+	var raw json.RawMessage
+	e := json.Unmarshal(data, &raw)
+	var v interface{}
+	if e == nil {
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.UseNumber()
+		e = dec.Decode(&v)
+	}
+	if e != nil {
+		pe := &ParseError{Err: e}
+		switch je := e.(type) {
+		case *json.SyntaxError:
+			// the error is in the byte before Offset, unless the input ended early
+			offset := je.Offset - 1
+			if offset == int64(len(data))-1 && strings.HasPrefix(je.Error(), "unexpected end") {
+				offset = int64(len(data))
+			}
+			pe.Line, pe.Column = position(data, offset)
+		case *json.UnmarshalTypeError:
+			pe.Line, pe.Column = position(data, je.Offset-1)
+		}
+		return nil, pe
+	}
+
+	// Get this as a map
+	v = normalise(v)
+	nested, ok := v.(map[string]interface{})
+	if !ok {
+		line, column := position(data, int64(len(data)-len(bytes.TrimLeft(data, " \t\r\n"))))
+		return nil, &ParseError{Line: line, Column: column, Err: fmt.Errorf("expected an object at the top level, got %s", describe(v))}
+	}
+	return nested, nil
+}
+
+// position returns the line and column (counting from 1, in bytes) of offset in data.
+func position(data []byte, offset int64) (int, int) {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	before := data[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := int(offset) - (bytes.LastIndexByte(before, '\n') + 1) + 1
+	return line, column
+}
+
+// stripJSONC turns JSONC into JSON, by replacing comments and trailing commas with spaces.
+// Newlines are kept, so that the positions in errors are those of the original.
+func stripJSONC(data []byte) []byte {
+	out := make([]byte, len(data))
+	copy(out, data)
+
+	// lastComma is the position of a comma that may turn out to be trailing, or -1
+	lastComma := -1
+	for i := 0; i < len(out); i++ {
+		switch c := out[i]; {
+		case c == '"':
+			lastComma = -1
+			// skip the string, including escaped quotes
+			for i++; i < len(out) && out[i] != '"'; i++ {
+				if out[i] == '\\' {
+					i++
+				}
+			}
+		case c == '/' && i+1 < len(out) && out[i+1] == '/':
+			for ; i < len(out) && out[i] != '\n'; i++ {
+				out[i] = ' '
+			}
+		case c == '/' && i+1 < len(out) && out[i+1] == '*':
+			end := bytes.Index(out[i+2:], []byte("*/"))
+			if end < 0 {
+				// unterminated; leave it for the JSON parser to report
+				return out
+			}
+			for j := i; j < i+2+end+2; j++ {
+				if out[j] != '\n' {
+					out[j] = ' '
+				}
+			}
+			i += 2 + end + 1
+		case c == ',':
+			lastComma = i
+		case c == '}' || c == ']':
+			if lastComma >= 0 {
+				out[lastComma] = ' '
+			}
+			lastComma = -1
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+		default:
+			lastComma = -1
+		}
+	}
+	return out
+}
+
+func decodeYAML(data []byte) (map[string]interface{}, error) {
+	var v interface{}
+	e := yaml.Unmarshal(data, &v)
+	if e != nil {
+		return nil, e
+	}
+	if v == nil {
+		// empty document
+		return map[string]interface{}{}, nil
+	}
+
+	v = normalise(v)
+	nested, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("top level of YAML document is not a mapping")
+	}
+	return nested, nil
+}
+
+func decodeTOML(data []byte) (map[string]interface{}, error) {
+	var v map[string]interface{}
+	_, e := toml.Decode(string(data), &v)
+	if e != nil {
+		return nil, e
+	}
+	return normalise(v).(map[string]interface{}), nil
+}
+
+// normalise converts the values produced by the decoders into the same shapes whatever
+// the format: maps keyed by string, []interface{} slices, and numbers as float64, or as
+// int64 or uint64 for integers too large for a float64 to hold exactly (see intNumber).
+// This keeps Get and the typed getters independent of the file format.
+func normalise(v interface{}) interface{} {
+	switch vv := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(vv))
+		for k, x := range vv {
+			m[fmt.Sprintf("%v", k)] = normalise(x)
+		}
+		return m
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(vv))
+		for k, x := range vv {
+			m[k] = normalise(x)
+		}
+		return m
+	case []map[string]interface{}:
+		s := make([]interface{}, len(vv))
+		for i, x := range vv {
+			s[i] = normalise(x)
+		}
+		return s
+	case []interface{}:
+		s := make([]interface{}, len(vv))
+		for i, x := range vv {
+			s[i] = normalise(x)
+		}
+		return s
+	case json.Number:
+		if n, e := parseNumber(vv.String()); e == nil {
+			return n
+		}
+		// out of the range of a float64; keep the text rather than lose it
+		return vv.String()
+	case int:
+		return intNumber(int64(vv))
+	case int64:
+		return intNumber(vv)
+	case uint64:
+		return uintNumber(vv)
+	case float32:
+		return float64(vv)
+	}
+	return v
+}
+
+// maxExactInt is the largest integer up to which every integer can be held exactly by a
+// float64.
+const maxExactInt = 1 << 53
+
+// intNumber returns the value stored for the integer i: a float64, as for any other number,
+// unless i is too large for a float64 to hold exactly, as for snowflake IDs, in which case
+// i itself, so that it isn't rounded.
+func intNumber(i int64) interface{} {
+	if i >= -maxExactInt && i <= maxExactInt {
+		return float64(i)
+	}
+	return i
+}
+
+// uintNumber is intNumber for unsigned integers, which are kept as uint64 only if they are
+// too large for an int64.
+func uintNumber(u uint64) interface{} {
+	if u <= math.MaxInt64 {
+		return intNumber(int64(u))
+	}
+	return u
+}
+
+// parseNumber parses a decimal number such as a JSON number, giving the value stored for
+// it. Integers are stored as intNumber and uintNumber do, and other numbers as float64.
+func parseNumber(s string) (interface{}, error) {
+	if !strings.ContainsAny(s, ".eE") {
+		if i, e := strconv.ParseInt(s, 10, 64); e == nil {
+			return intNumber(i), nil
+		}
+		if u, e := strconv.ParseUint(s, 10, 64); e == nil {
+			return uintNumber(u), nil
+		}
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+// decodeINI parses INI data. Keys in a [section] are placed under that section, so
+// "host" in [db] becomes "db.host". Keys before the first section are top level.
+// Values are always strings.
+func decodeINI(data []byte) (map[string]interface{}, error) {
+	result := make(map[string]interface{})
+	section := ""
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == ';' || line[0] == '#' {
+			continue
+		}
+
+		if line[0] == '[' {
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("ini line %d: unterminated section header", lineNo)
+			}
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+
+		i := strings.IndexAny(line, "=:")
+		if i < 0 {
+			return nil, fmt.Errorf("ini line %d: expected key = value", lineNo)
+		}
+		key := strings.TrimSpace(line[:i])
+		value := strings.TrimSpace(line[i+1:])
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		if section != "" {
+			key = section + "." + key
+		}
+		result[key] = value
+	}
+	if e := scanner.Err(); e != nil {
+		return nil, e
+	}
+	return result, nil
+}
+
+// decodeProperties parses Java .properties data. Keys are conventionally dot-delimited
+// already, so they are used as-is. Values are always strings.
+func decodeProperties(data []byte) (map[string]interface{}, error) {
+	result := make(map[string]interface{})
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	logical := ""
+	for scanner.Scan() {
+		line := strings.TrimLeft(scanner.Text(), " \t\f")
+		if logical == "" && (line == "" || line[0] == '#' || line[0] == '!') {
+			continue
+		}
+
+		// a line ending in an odd number of backslashes continues on the next line
+		trailing := len(line) - len(strings.TrimRight(line, "\\"))
+		if trailing%2 == 1 {
+			logical += line[:len(line)-1]
+			continue
+		}
+		logical += line
+
+		key, value, e := splitProperty(logical)
+		logical = ""
+		if e != nil {
+			return nil, e
+		}
+		result[key] = value
+	}
+	if e := scanner.Err(); e != nil {
+		return nil, e
+	}
+	if logical != "" {
+		key, value, e := splitProperty(logical)
+		if e != nil {
+			return nil, e
+		}
+		result[key] = value
+	}
+	return result, nil
+}
+
+// splitProperty splits a logical .properties line at the first unescaped '=', ':' or
+// whitespace, and unescapes both halves.
+func splitProperty(line string) (string, string, error) {
+	end := len(line)
+	for i := 0; i < len(line); i++ {
+		if line[i] == '\\' {
+			i++
+			continue
+		}
+		if line[i] == '=' || line[i] == ':' || line[i] == ' ' || line[i] == '\t' || line[i] == '\f' {
+			end = i
+			break
+		}
+	}
+	key := line[:end]
+	rest := strings.TrimLeft(line[end:], " \t\f")
+	if len(rest) > 0 && (rest[0] == '=' || rest[0] == ':') {
+		rest = strings.TrimLeft(rest[1:], " \t\f")
+	}
+
+	k, e := unescapeProperty(key)
+	if e != nil {
+		return "", "", e
+	}
+	v, e := unescapeProperty(rest)
+	if e != nil {
+		return "", "", e
+	}
+	return k, v, nil
+}
+
+func unescapeProperty(s string) (string, error) {
+	if !strings.Contains(s, "\\") {
+		return s, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i == len(s)-1 {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 'f':
+			b.WriteByte('\f')
+		case 'u':
+			if i+4 >= len(s) {
+				return "", fmt.Errorf("invalid unicode escape in %q", s)
+			}
+			r, e := strconv.ParseUint(s[i+1:i+5], 16, 32)
+			if e != nil {
+				return "", fmt.Errorf("invalid unicode escape in %q", s)
+			}
+			b.WriteRune(rune(r))
+			i += 4
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,250 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileFormats(t *testing.T) {
+	for _, path := range []string{"./test.json", "./test.yaml", "./test.toml", "./test.ini", "./test.properties"} {
+		conf, e := ReadFromFile(path)
+		if e != nil {
+			t.Errorf("Error reading %s: '%s'", path, e)
+			continue
+		}
+
+		if conf.GetString("test1.intprop") != "123" {
+			t.Errorf("Expected test1.intprop from %s to have value \"123\", has '%v'", path, conf.Get("test1.intprop"))
+		}
+		if conf.GetString("test1.strprop") != "str" {
+			t.Errorf("Expected test1.strprop from %s to have value \"str\", has '%v'", path, conf.Get("test1.strprop"))
+		}
+		if conf.GetString("test1.boolprop") != "true" {
+			t.Errorf("Expected test1.boolprop from %s to have value \"true\", has '%v'", path, conf.Get("test1.boolprop"))
+		}
+		if conf.GetString("test1.child1.child1_2.x") != "c1_2" {
+			t.Errorf("Expected test1.child1.child1_2.x from %s to have value \"c1_2\", has '%v'", path, conf.Get("test1.child1.child1_2.x"))
+		}
+		if conf.GetString("test2.test2prop") != "t2prop" {
+			t.Errorf("Expected test2.test2prop from %s to have value \"t2prop\", has '%v'", path, conf.Get("test2.test2prop"))
+		}
+	}
+}
+
+func TestTypedFileFormats(t *testing.T) {
+	// numbers from YAML and TOML are returned as float64, the same as from JSON
+	for _, path := range []string{"./test.yaml", "./test.toml"} {
+		conf, _ := ReadFromFile(path)
+		if conf.GetInt("test1.intprop") != 123 {
+			t.Errorf("Expected test1.intprop from %s to be numeric 123, has '%#v'", path, conf.Get("test1.intprop"))
+		}
+		if !conf.GetBool("test1.boolprop") {
+			t.Errorf("Expected test1.boolprop from %s to be true", path)
+		}
+		if a, ok := conf.Get("test1.arrayprop").([]interface{}); !ok || len(a) != 3 {
+			t.Errorf("Expected test1.arrayprop from %s to be an array of length 3, has '%#v'", path, conf.Get("test1.arrayprop"))
+		}
+	}
+}
+
+func TestExplicitFormat(t *testing.T) {
+	conf := NewConfig()
+	e := conf.AddFileWithFormat("./test.ini", FormatINI, "legacy", false)
+	if e != nil {
+		t.Errorf("Error reading test.ini: '%s'", e)
+	}
+	if conf.GetString("legacy.test1.strprop") != "str" {
+		t.Errorf("Expected legacy.test1.strprop to have value \"str\"")
+	}
+
+	e = conf.AddFileWithFormat("./test.ini", "xml", "", false)
+	if e == nil {
+		t.Errorf("Expected an error for an unknown format, didn't get one")
+	}
+}
+
+func TestJSONParseErrors(t *testing.T) {
+	dir := t.TempDir()
+	for name, test := range map[string]struct {
+		content string
+		line    int
+		column  int
+		message string
+	}{
+		"syntax.json":    {"{\n  \"a\": 1,\n  \"b\" 2\n}", 3, 7, "invalid character '2'"},
+		"truncated.json": {"{\n  \"a\": [1, 2", 2, 13, "unexpected end of JSON input"},
+		"array.json":     {"\n  [1, 2]", 2, 3, "expected an object at the top level, got array"},
+		"scalar.json":    {"42", 1, 1, "got number 42"},
+		"trailing.json":  {"{\"a\": 1,}", 1, 9, "invalid character '}'"},
+	} {
+		path := filepath.Join(dir, name)
+		os.WriteFile(path, []byte(test.content), 0644)
+
+		e := NewConfig().AddFile(path, "", false)
+		var pe *ParseError
+		if !errors.As(e, &pe) {
+			t.Errorf("%s: Expected a *ParseError, got %v", name, e)
+			continue
+		}
+		if pe.File != path || pe.Line != test.line || pe.Column != test.column || !strings.Contains(e.Error(), test.message) {
+			t.Errorf("%s: Expected an error at %d:%d containing %q, got %s", name, test.line, test.column, test.message, e)
+		}
+		if !strings.HasPrefix(e.Error(), "config: "+path+":") {
+			t.Errorf("%s: Expected the error to name the file, got %s", name, e)
+		}
+	}
+}
+
+func TestParseErrorsOfOtherFormats(t *testing.T) {
+	dir := t.TempDir()
+	for _, test := range []struct {
+		name, format, content, message string
+	}{
+		{"list.yaml", FormatYAML, "- a\n", "top level of YAML document is not a mapping"},
+		{"bad.toml", FormatTOML, "a = \n", ""},
+		{"bad.ini", FormatINI, "[section\n", "ini line 1: unterminated section header"},
+		{"app.xml", "xml", "<a/>", `unknown file format "xml"`},
+	} {
+		path := filepath.Join(dir, test.name)
+		os.WriteFile(path, []byte(test.content), 0644)
+
+		e := NewConfig().AddFileWithFormat(path, test.format, "", false)
+		var pe *ParseError
+		if !errors.As(e, &pe) || pe.File != path {
+			t.Errorf("%s: Expected a *ParseError for the file, got %v", test.name, e)
+			continue
+		}
+		// the message has a single prefix
+		if !strings.HasPrefix(e.Error(), "config: "+path+": ") || strings.Count(e.Error(), "config: ") != 1 || !strings.Contains(e.Error(), test.message) {
+			t.Errorf("%s: Expected one prefix and %q, got %s", test.name, test.message, e)
+		}
+	}
+}
+
+func TestJSONC(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.jsonc")
+	os.WriteFile(path, []byte(`{
+	// the database
+	"db": {
+		"host": "db1", /* primary */
+		"url": "http://example.com/a,}",
+		"tags": ["a", "b",],
+	},
+	/* unused
+	"old": true, */
+}
+`), 0644)
+
+	conf := NewConfig()
+	if e := conf.AddFile(path, "", false); e != nil {
+		t.Fatal(e)
+	}
+	if conf.GetString("db.host") != "db1" || conf.GetString("db.url") != "http://example.com/a,}" || conf.Len("db.tags") != 2 || conf.HasKey("old") {
+		t.Errorf("Expected comments and trailing commas to be ignored, got %v", conf.snapshot())
+	}
+
+	// errors point into the original file
+	os.WriteFile(path, []byte("{\n  // comment\n  \"a\": tru\n}"), 0644)
+	var pe *ParseError
+	if e := NewConfig().AddFile(path, "", false); !errors.As(e, &pe) || pe.Line != 3 || pe.Column != 11 {
+		t.Errorf("Expected an error at 3:11, got %v", e)
+	}
+
+	// a .json file can be read as JSONC explicitly
+	json := filepath.Join(dir, "app.json")
+	os.WriteFile(json, []byte(`{"a": 1, // one
+}`), 0644)
+	if e := NewConfig().AddFileWithFormat(json, FormatJSONC, "", false); e != nil {
+		t.Errorf("Expected JSONC in a .json file to be read, got %v", e)
+	}
+
+	// JSON5 isn't supported, so .json5 files are read as JSON like other unknown extensions
+	if format := FormatForPath("app.json5"); format != FormatJSON {
+		t.Errorf("Expected .json5 to be read as JSON, got %s", format)
+	}
+	if knownExtension("app.json5") {
+		t.Errorf("Expected .json5 files not to be read by AddDirectory")
+	}
+}
+
+func TestNumberPrecision(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"ids.json": `{"id": 1234567890123456789, "max": 18446744073709551615, "small": 42, "ratio": 0.25, "neg": -9007199254740993, "huge": 1e400}`,
+		"ids.yaml": "id: 1234567890123456789\nmax: 18446744073709551615\n",
+		"ids.toml": "id = 1234567890123456789\n",
+	})
+
+	for _, name := range []string{"ids.json", "ids.yaml", "ids.toml"} {
+		conf := NewConfig()
+		if e := conf.AddFile(filepath.Join(dir, name), "", false); e != nil {
+			t.Fatal(e)
+		}
+		if i, e := conf.GetInt64E("id"); e != nil || i != 1234567890123456789 {
+			t.Errorf("%s: Expected id to be 1234567890123456789, got %d, %v", name, i, e)
+		}
+		if s := conf.GetString("id"); s != "1234567890123456789" {
+			t.Errorf("%s: Expected id as a string to be exact, got %s", name, s)
+		}
+	}
+
+	conf := NewConfig()
+	if e := conf.AddFile(filepath.Join(dir, "ids.json"), "", false); e != nil {
+		t.Fatal(e)
+	}
+
+	// small numbers are float64, as they always were
+	if v, ok := conf.Get("small").(float64); !ok || v != 42 {
+		t.Errorf("Expected small to be float64 42, got %#v", conf.Get("small"))
+	}
+	if conf.GetFloat64("ratio") != 0.25 {
+		t.Errorf("Expected ratio to be 0.25, got %#v", conf.Get("ratio"))
+	}
+	if i := conf.GetInt64("neg"); i != -9007199254740993 {
+		t.Errorf("Expected neg to be -9007199254740993, got %d", i)
+	}
+	if u, e := conf.GetUintE("max"); e != nil || uint64(u) != 18446744073709551615 {
+		t.Errorf("Expected max to be the largest uint64, got %d, %v", u, e)
+	}
+
+	// overflow is reported, not truncated
+	if _, e := conf.GetInt64E("max"); e == nil || !strings.Contains(e.Error(), "overflows") {
+		t.Errorf("Expected max to overflow an int64, got %v", e)
+	}
+	if _, e := Get[int32](conf, "id"); e == nil || !strings.Contains(e.Error(), "overflows") {
+		t.Errorf("Expected id to overflow an int32, got %v", e)
+	}
+	if _, e := Get[int8](conf, "small"); e != nil {
+		t.Errorf("Expected small to fit in an int8, got %v", e)
+	}
+	if _, e := conf.GetFloat64E("huge"); e == nil {
+		t.Errorf("Expected huge not to be a float64")
+	}
+
+	// and strings that are too large too
+	conf.AddDefaultOverride("big", "99999999999999999999")
+	if _, e := conf.GetInt64E("big"); e == nil || !strings.Contains(e.Error(), "overflows int64") {
+		t.Errorf("Expected big to overflow, got %v", e)
+	}
+
+	// as are numbers inferred from the environment
+	t.Setenv("PRECISION_ID", "1234567890123456789")
+	t.Setenv("PRECISION_IDS", "[1234567890123456789]")
+	conf.AddEnvironmentMapped(EnvOptions{Prefix: "PRECISION_", InferTypes: true})
+	if conf.Get("id") != int64(1234567890123456789) || conf.GetInt64("ids[0]") != 1234567890123456789 {
+		t.Errorf("Expected id and ids[0] from the environment to be exact, got %#v and %#v", conf.Get("id"), conf.Get("ids"))
+	}
+
+	// encoding writes them back exactly
+	var buf strings.Builder
+	if e := conf.Encode(&buf, FormatJSON); e != nil {
+		t.Fatal(e)
+	}
+	if !strings.Contains(buf.String(), "1234567890123456789") || !strings.Contains(buf.String(), "18446744073709551615") {
+		t.Errorf("Expected the IDs to be encoded exactly, got %s", buf.String())
+	}
+}
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"errors"
+	"reflect"
+)
+
+// Get returns the value of key converted to T. Values are converted as for Unmarshal, so
+// T can be any of the types it supports, including types implementing
+// encoding.TextUnmarshaler, which are given the value as text. The error is a *KeyError,
+// wrapping ErrNotFound if the key is not set, or an *InterpolationError.
+//
+//	port, e := config.Get[int](conf, "db.port")
+func Get[T any](c Config, key string) (T, error) {
+	var v T
+	e := c.getAs(key, &v)
+	return v, e
+}
+
+// MustGet is like Get, but panics if the key is not set or can't be converted. It is
+// meant for settings without which a program can't start.
+func MustGet[T any](c Config, key string) T {
+	v, e := Get[T](c, key)
+	if e != nil {
+		panic(e)
+	}
+	return v
+}
+
+// GetOr is like Get, but returns def if the key is not set or can't be converted.
+func GetOr[T any](c Config, key string, def T) T {
+	v, e := Get[T](c, key)
+	if e != nil {
+		return def
+	}
+	return v
+}
+
+// getAs reads key and converts it into the value pointed to by out.
+func (c Config) getAs(key string, out interface{}) error {
+	rv := reflect.ValueOf(out).Elem()
+
+	v, e := c.value(key)
+	if errors.Is(e, ErrNotFound) && rv.Kind() == reflect.Map {
+		// maps are flattened into separate keys; gather them up again
+		var m map[string]interface{}
+		if m, e = c.subMap(key); e == nil && len(m) == 0 {
+			e = &KeyError{Key: key, Err: ErrNotFound}
+		}
+		v = m
+	}
+	if e != nil {
+		return e
+	}
+	if e := convertInto(v, rv); e != nil {
+		return &KeyError{Key: key, Err: e}
+	}
+	return nil
+}
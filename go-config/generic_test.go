@@ -0,0 +1,45 @@
+package config
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestGenericGet(t *testing.T) {
+	conf, _ := ReadFromFile("./test.json")
+	conf.AddDefault("listen", "127.0.0.1")
+	conf.AddDefault("timeout", "5s")
+
+	i, e := Get[int](conf, "test1.intprop")
+	if e != nil || i != 123 {
+		t.Errorf("Expected test1.intprop to be 123, got %d and '%v'", i, e)
+	}
+	if d := MustGet[time.Duration](conf, "timeout"); d != 5*time.Second {
+		t.Errorf("Expected timeout to be 5s, got %s", d)
+	}
+	// net.IP implements encoding.TextUnmarshaler
+	if ip := MustGet[net.IP](conf, "listen"); !ip.Equal(net.IPv4(127, 0, 0, 1)) {
+		t.Errorf("Expected listen to be 127.0.0.1, got %s", ip)
+	}
+	if s := GetOr[[]string](conf, "non-existent", []string{"x"}); len(s) != 1 || s[0] != "x" {
+		t.Errorf("Expected GetOr to return the default, got %v", s)
+	}
+
+	_, e = Get[bool](conf, "test1.strprop")
+	var ke *KeyError
+	if !errors.As(e, &ke) || ke.Key != "test1.strprop" {
+		t.Errorf("Expected a *KeyError naming test1.strprop, got '%v'", e)
+	}
+	if _, e = Get[net.IP](conf, "test1.strprop"); e == nil {
+		t.Errorf("Expected an error reading test1.strprop as a net.IP")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected MustGet to panic for a missing key")
+		}
+	}()
+	MustGet[int](conf, "non-existent")
+}
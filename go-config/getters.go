@@ -0,0 +1,316 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// ErrNotFound is returned by the ...E getters when a key is not set.
+var ErrNotFound = errors.New("key not found")
+
+// KeyError is returned by the ...E getters, naming the key that could not be read.
+type KeyError struct {
+	Key string
+	Err error
+}
+
+func (e *KeyError) Error() string {
+	return fmt.Sprintf("config: %s: %s", e.Key, e.Err)
+}
+
+func (e *KeyError) Unwrap() error {
+	return e.Err
+}
+
+// The getters come in three forms. GetX returns the zero value if the key is missing or
+// can't be converted, GetXE returns a *KeyError instead (wrapping ErrNotFound if the key
+// is missing), or an *InterpolationError if a reference in it can't be expanded, and
+// GetXOr returns the given default instead. Most are shorthands for the generic Get,
+// MustGet and GetOr functions.
+
+// GetString returns a key from the configuration (using Get), but returning it as a string.
+// If the key is not defined, it returns "".
+func (c Config) GetString(key string) string {
+	return c.GetStringOr(key, "")
+}
+
+// GetStringE returns a key as a string, or an error if it's not set.
+func (c Config) GetStringE(key string) (string, error) {
+	v, e := c.value(key)
+	if e != nil {
+		return "", e
+	}
+	return fmt.Sprintf("%v", v), nil
+}
+
+// GetStringOr returns a key as a string, or def if it's not set.
+func (c Config) GetStringOr(key string, def string) string {
+	if s, e := c.GetStringE(key); e == nil {
+		return s
+	}
+	return def
+}
+
+// GetInt returns a key from the configuration as an integer. Integer values in the
+// json file are retrieved as float64, and strings are parsed. It returns 0 if the key is not
+// set, or is not an integer.
+func (c Config) GetInt(key string) int {
+	return c.GetIntOr(key, 0)
+}
+
+// GetIntE returns a key as an int, or an error if it's not set, not an integer or too large.
+func (c Config) GetIntE(key string) (int, error) {
+	return Get[int](c, key)
+}
+
+// GetIntOr returns a key as an int, or def if it's not set or not an integer.
+func (c Config) GetIntOr(key string, def int) int {
+	if i, e := c.GetIntE(key); e == nil {
+		return i
+	}
+	return def
+}
+
+// AsInt returns a key as an int, or an error if it's not set or not an integer.
+//
+// Deprecated: use GetIntE.
+func (c Config) AsInt(key string) (int, error) {
+	return c.GetIntE(key)
+}
+
+// AsString returns a key as a string, or "" if it's not set.
+//
+// Deprecated: use GetString.
+func (c Config) AsString(key string) string {
+	return c.GetString(key)
+}
+
+// GetInt64 returns a key as an int64, or 0 if it's not set or not an integer.
+func (c Config) GetInt64(key string) int64 {
+	return c.GetInt64Or(key, 0)
+}
+
+// GetInt64E returns a key as an int64, or an error if it's not set or not an integer.
+func (c Config) GetInt64E(key string) (int64, error) {
+	return Get[int64](c, key)
+}
+
+// GetInt64Or returns a key as an int64, or def if it's not set or not an integer.
+func (c Config) GetInt64Or(key string, def int64) int64 {
+	if i, e := c.GetInt64E(key); e == nil {
+		return i
+	}
+	return def
+}
+
+// GetUint returns a key as a uint, or 0 if it's not set or not a non-negative integer.
+func (c Config) GetUint(key string) uint {
+	return c.GetUintOr(key, 0)
+}
+
+// GetUintE returns a key as a uint, or an error if it's not set or not a non-negative integer.
+func (c Config) GetUintE(key string) (uint, error) {
+	return Get[uint](c, key)
+}
+
+// GetUintOr returns a key as a uint, or def if it's not set or not a non-negative integer.
+func (c Config) GetUintOr(key string, def uint) uint {
+	if u, e := c.GetUintE(key); e == nil {
+		return u
+	}
+	return def
+}
+
+// GetFloat64 returns a key as a float64, or 0 if it's not set or not a number.
+func (c Config) GetFloat64(key string) float64 {
+	return c.GetFloat64Or(key, 0)
+}
+
+// GetFloat64E returns a key as a float64, or an error if it's not set or not a number.
+func (c Config) GetFloat64E(key string) (float64, error) {
+	return Get[float64](c, key)
+}
+
+// GetFloat64Or returns a key as a float64, or def if it's not set or not a number.
+func (c Config) GetFloat64Or(key string, def float64) float64 {
+	if f, e := c.GetFloat64E(key); e == nil {
+		return f
+	}
+	return def
+}
+
+// GetBool returns a key as a bool. Strings such as "true" and "0" are parsed. It returns
+// false if the key is not set or not a bool.
+func (c Config) GetBool(key string) bool {
+	return c.GetBoolOr(key, false)
+}
+
+// GetBoolE returns a key as a bool, or an error if it's not set or not a bool.
+func (c Config) GetBoolE(key string) (bool, error) {
+	return Get[bool](c, key)
+}
+
+// GetBoolOr returns a key as a bool, or def if it's not set or not a bool.
+func (c Config) GetBoolOr(key string, def bool) bool {
+	if b, e := c.GetBoolE(key); e == nil {
+		return b
+	}
+	return def
+}
+
+// GetDuration returns a key such as "30s" or "1h30m" as a time.Duration. Plain numbers
+// are taken to be seconds. It returns 0 if the key is not set or not a duration.
+func (c Config) GetDuration(key string) time.Duration {
+	return c.GetDurationOr(key, 0)
+}
+
+// GetDurationE returns a key as a time.Duration, or an error if it's not set or not a duration.
+func (c Config) GetDurationE(key string) (time.Duration, error) {
+	return Get[time.Duration](c, key)
+}
+
+// GetDurationOr returns a key as a time.Duration, or def if it's not set or not a duration.
+func (c Config) GetDurationOr(key string, def time.Duration) time.Duration {
+	if d, e := c.GetDurationE(key); e == nil {
+		return d
+	}
+	return def
+}
+
+// GetTime returns a key in RFC 3339 format (or just a date) as a time.Time. Plain numbers
+// are taken to be seconds since the Unix epoch. It returns the zero time if the key is not
+// set or not a time.
+func (c Config) GetTime(key string) time.Time {
+	return c.GetTimeOr(key, time.Time{})
+}
+
+// GetTimeE returns a key as a time.Time, or an error if it's not set or not a time.
+func (c Config) GetTimeE(key string) (time.Time, error) {
+	return Get[time.Time](c, key)
+}
+
+// GetTimeOr returns a key as a time.Time, or def if it's not set or not a time.
+func (c Config) GetTimeOr(key string, def time.Time) time.Time {
+	if t, e := c.GetTimeE(key); e == nil {
+		return t
+	}
+	return def
+}
+
+// GetSize returns a size such as "10MB" or "512KiB" as a number of bytes. Units with an "i"
+// are powers of 1024, the others powers of 1000; plain numbers are bytes. It returns 0 if
+// the key is not set or not a size.
+func (c Config) GetSize(key string) int64 {
+	return c.GetSizeOr(key, 0)
+}
+
+// GetSizeE returns a key as a number of bytes, or an error if it's not set or not a size.
+func (c Config) GetSizeE(key string) (int64, error) {
+	v, e := c.value(key)
+	if e != nil {
+		return 0, e
+	}
+	size, e := toSize(v)
+	if e != nil {
+		return 0, &KeyError{Key: key, Err: e}
+	}
+	return size, nil
+}
+
+// GetSizeOr returns a key as a number of bytes, or def if it's not set or not a size.
+func (c Config) GetSizeOr(key string, def int64) int64 {
+	if size, e := c.GetSizeE(key); e == nil {
+		return size
+	}
+	return def
+}
+
+// GetURL returns a key as a parsed URL, or nil if it's not set or not a URL.
+func (c Config) GetURL(key string) *url.URL {
+	return c.GetURLOr(key, nil)
+}
+
+// GetURLE returns a key as a parsed URL, or an error if it's not set or not a URL.
+func (c Config) GetURLE(key string) (*url.URL, error) {
+	v, e := c.value(key)
+	if e != nil {
+		return nil, e
+	}
+	u, e := toURL(v)
+	if e != nil {
+		return nil, &KeyError{Key: key, Err: e}
+	}
+	return u, nil
+}
+
+// GetURLOr returns a key as a parsed URL, or def if it's not set or not a URL.
+func (c Config) GetURLOr(key string, def *url.URL) *url.URL {
+	if u, e := c.GetURLE(key); e == nil {
+		return u
+	}
+	return def
+}
+
+// GetStringArray returns an array as a []string, converting its elements to strings.
+// A string value is split on commas. It returns an empty slice if the key is not set or
+// not an array.
+func (c Config) GetStringArray(key string) []string {
+	return c.GetStringArrayOr(key, []string{})
+}
+
+// GetStringArrayE returns an array as a []string, or an error if it's not set or not an array.
+func (c Config) GetStringArrayE(key string) ([]string, error) {
+	return Get[[]string](c, key)
+}
+
+// GetStringArrayOr returns an array as a []string, or def if it's not set or not an array.
+func (c Config) GetStringArrayOr(key string, def []string) []string {
+	if s, e := c.GetStringArrayE(key); e == nil {
+		return s
+	}
+	return def
+}
+
+// GetIntArray returns an array of integers as an []int. A string value is split on commas.
+// It returns an empty slice if the key is not set or not an array of integers.
+func (c Config) GetIntArray(key string) []int {
+	return c.GetIntArrayOr(key, []int{})
+}
+
+// GetIntArrayE returns an array as an []int, or an error if it's not set or not an array
+// of integers.
+func (c Config) GetIntArrayE(key string) ([]int, error) {
+	return Get[[]int](c, key)
+}
+
+// GetIntArrayOr returns an array as an []int, or def if it's not set or not an array of
+// integers.
+func (c Config) GetIntArrayOr(key string, def []int) []int {
+	if s, e := c.GetIntArrayE(key); e == nil {
+		return s
+	}
+	return def
+}
+
+// GetStringMap returns the keys under key as a map[string]string, so with "labels.env"
+// and "labels.team" set, GetStringMap("labels") has the keys "env" and "team". It returns
+// an empty map if there are no such keys.
+func (c Config) GetStringMap(key string) map[string]string {
+	return c.GetStringMapOr(key, map[string]string{})
+}
+
+// GetStringMapE returns the keys under key as a map[string]string, or an error if there
+// are none.
+func (c Config) GetStringMapE(key string) (map[string]string, error) {
+	return Get[map[string]string](c, key)
+}
+
+// GetStringMapOr returns the keys under key as a map[string]string, or def if there are none.
+func (c Config) GetStringMapOr(key string, def map[string]string) map[string]string {
+	if m, e := c.GetStringMapE(key); e == nil {
+		return m
+	}
+	return def
+}
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTypedGetters(t *testing.T) {
+	conf, _ := ReadFromFile("./test.json")
+	conf.AddDefault("timeout", "1m30s")
+	conf.AddDefault("started", "2016-01-02T15:04:05Z")
+	conf.AddDefault("limit", "10MB")
+	conf.AddDefault("buffer", "4KiB")
+	conf.AddDefault("endpoint", "https://example.com:8443/api")
+	conf.AddDefault("ports", "80, 443")
+	conf.AddDefault("ratio", "0.75")
+	conf.AddDefault("labels.env", "prod")
+	conf.AddDefault("labels.team", "web")
+
+	if conf.GetInt64("test1.intprop") != 123 || conf.GetUint("test1.intprop") != 123 {
+		t.Errorf("Expected test1.intprop to be 123 via GetInt64 and GetUint")
+	}
+	if conf.GetFloat64("ratio") != 0.75 {
+		t.Errorf("Expected ratio to be 0.75, got %v", conf.GetFloat64("ratio"))
+	}
+	if conf.GetDuration("timeout") != 90*time.Second || conf.GetDuration("test1.child2.menuTTL") != 30*time.Second {
+		t.Errorf("Expected timeout to be 1m30s and menuTTL 30s, got %s and %s", conf.GetDuration("timeout"), conf.GetDuration("test1.child2.menuTTL"))
+	}
+	if !conf.GetTime("started").Equal(time.Date(2016, 1, 2, 15, 4, 5, 0, time.UTC)) {
+		t.Errorf("Expected started to be 2016-01-02T15:04:05Z, got %s", conf.GetTime("started"))
+	}
+	if conf.GetSize("limit") != 10000000 || conf.GetSize("buffer") != 4096 {
+		t.Errorf("Expected limit to be 10000000 bytes and buffer 4096, got %d and %d", conf.GetSize("limit"), conf.GetSize("buffer"))
+	}
+	if u := conf.GetURL("endpoint"); u == nil || u.Port() != "8443" {
+		t.Errorf("Expected endpoint to be a URL with port 8443, got %v", u)
+	}
+	if !reflect.DeepEqual(conf.GetIntArray("ports"), []int{80, 443}) {
+		t.Errorf("Expected ports to be [80 443], got %v", conf.GetIntArray("ports"))
+	}
+	// JSON arrays are []interface{}, and their elements are converted
+	if !reflect.DeepEqual(conf.GetStringArray("test1.arrayprop"), []string{"a", "b", "999"}) {
+		t.Errorf("Expected test1.arrayprop to be [a b 999], got %v", conf.GetStringArray("test1.arrayprop"))
+	}
+	if !reflect.DeepEqual(conf.GetStringMap("labels"), map[string]string{"env": "prod", "team": "web"}) {
+		t.Errorf("Expected labels to be map[env:prod team:web], got %v", conf.GetStringMap("labels"))
+	}
+}
+
+func TestGetterErrors(t *testing.T) {
+	conf, _ := ReadFromFile("./test.json")
+	conf.AddDefault("limit", "10 parsecs")
+	conf.AddDefault("negative", "-1")
+
+	_, e := conf.GetIntE("non-existent")
+	if !errors.Is(e, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound reading non-existent, got '%v'", e)
+	}
+	if _, e = conf.GetBoolE("test1.strprop"); e == nil {
+		t.Errorf("Expected an error reading test1.strprop as a bool")
+	}
+	if _, e = conf.GetSizeE("limit"); e == nil {
+		t.Errorf("Expected an error reading limit as a size")
+	}
+	if _, e = conf.GetUintE("negative"); e == nil {
+		t.Errorf("Expected an error reading negative as a uint")
+	}
+	if ke, ok := e.(*KeyError); !ok || ke.Key != "negative" {
+		t.Errorf("Expected a *KeyError naming negative, got '%v'", e)
+	}
+	if _, e = conf.GetIntArrayE("test1.arrayprop"); e == nil {
+		t.Errorf("Expected an error reading test1.arrayprop as an []int")
+	}
+
+	if conf.GetIntOr("non-existent", 42) != 42 || conf.GetIntOr("test1.strprop", 42) != 42 {
+		t.Errorf("Expected GetIntOr to return the default for missing and invalid keys")
+	}
+	if conf.GetDurationOr("non-existent", time.Minute) != time.Minute {
+		t.Errorf("Expected GetDurationOr to return the default for a missing key")
+	}
+	if conf.GetStringOr("test1.strprop", "x") != "str" {
+		t.Errorf("Expected GetStringOr to return the value of a key that is set")
+	}
+}
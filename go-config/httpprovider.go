@@ -0,0 +1,153 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// SourceHTTP is the kind of source for values from an HTTPProvider.
+const SourceHTTP = "http"
+
+// HTTPProvider provides the values of a JSON document fetched from a URL, such as a small
+// central configuration server. It sends the ETag of the last response in If-None-Match,
+// so an unchanged document isn't transferred again. When watched, it polls the URL every
+// Interval, backing off (doubling the interval, up to MaxBackoff) while requests fail.
+// Create one with NewHTTPProvider, and set the fields before adding it.
+type HTTPProvider struct {
+	URL string
+	// Client makes the requests. It defaults to http.DefaultClient; set a timeout on it.
+	Client *http.Client
+	// Header is added to each request, e.g. for an Authorization header.
+	Header http.Header
+	// Interval is how often the URL is polled when watched. It defaults to 30 seconds.
+	Interval time.Duration
+	// MaxBackoff is the longest interval between failed polls. It defaults to 5 minutes.
+	MaxBackoff time.Duration
+	// MaxSize is the size in bytes of the largest document accepted. It defaults to 1MB.
+	MaxSize int64
+
+	// mu guards the fields below, the document last fetched.
+	mu     sync.Mutex
+	etag   string
+	body   []byte
+	values map[string]interface{}
+}
+
+// NewHTTPProvider returns a provider for the JSON document at url.
+func NewHTTPProvider(url string) *HTTPProvider {
+	return &HTTPProvider{URL: url, Interval: 30 * time.Second, MaxBackoff: 5 * time.Minute, MaxSize: 1 << 20}
+}
+
+// Load fetches the document, or returns the values of the last one if it hasn't changed.
+func (p *HTTPProvider) Load() (map[string]interface{}, error) {
+	if _, e := p.fetch(); e != nil {
+		return nil, e
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.values, nil
+}
+
+func (p *HTTPProvider) Describe() (string, string) {
+	return SourceHTTP, p.URL
+}
+
+// Watch polls the URL until stop is closed, calling changed when the document changes.
+func (p *HTTPProvider) Watch(stop <-chan struct{}, changed func(), onError func(error)) {
+	interval, maxBackoff := p.Interval, p.MaxBackoff
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	if maxBackoff < interval {
+		maxBackoff = interval
+	}
+
+	delay := interval
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-timer.C:
+		}
+
+		updated, e := p.fetch()
+		if e != nil {
+			onError(e)
+			if delay *= 2; delay > maxBackoff {
+				delay = maxBackoff
+			}
+		} else {
+			delay = interval
+			if updated {
+				changed()
+			}
+		}
+		timer.Reset(delay)
+	}
+}
+
+// fetch requests the document, and reports whether it differs from the last one.
+func (p *HTTPProvider) fetch() (bool, error) {
+	req, e := http.NewRequest(http.MethodGet, p.URL, nil)
+	if e != nil {
+		return false, e
+	}
+	for k, v := range p.Header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Accept", "application/json")
+	p.mu.Lock()
+	if p.etag != "" && p.values != nil {
+		req.Header.Set("If-None-Match", p.etag)
+	}
+	p.mu.Unlock()
+
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, e := client.Do(req)
+	if e != nil {
+		return false, fmt.Errorf("config: %s", e)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified:
+		return false, nil
+	case resp.StatusCode != http.StatusOK:
+		return false, fmt.Errorf("config: GET %s: %s", p.URL, resp.Status)
+	}
+	maxSize := p.MaxSize
+	if maxSize <= 0 {
+		maxSize = 1 << 20
+	}
+	// read one byte more than allowed, to tell a document of the maximum size from a longer one
+	body, e := ioutil.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if e != nil {
+		return false, fmt.Errorf("config: GET %s: %s", p.URL, e)
+	}
+	if int64(len(body)) > maxSize {
+		return false, fmt.Errorf("config: GET %s: document is larger than %d bytes", p.URL, maxSize)
+	}
+	values, e := decode(body, FormatJSON)
+	if e != nil {
+		if pe, ok := e.(*ParseError); ok {
+			pe.File = p.URL
+		}
+		return false, e
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	updated := p.values == nil || !bytes.Equal(body, p.body)
+	p.etag, p.body, p.values = resp.Header.Get("ETag"), body, values
+	return updated, nil
+}
@@ -0,0 +1,177 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// includeKey is the property of a file that lists the files it includes.
+const includeKey = "$include"
+
+// AddDirectory adds the files in dir whose names match pattern (as for filepath.Match, e.g.
+// "*.json"), in lexical order, such as the drop-ins of a conf.d directory. Each file
+// overrides the settings of those before it, and of the sources already added, so name
+// them e.g. "10-base.json", "20-site.json". If pattern is "", all files with an extension
+// FormatForPath recognises are added. Hidden files and subdirectories are skipped. The
+// files are all read before any are added, so if one can't be read the config is left
+// unchanged. The files are read with a DirectoryProvider, so Reload lists the directory
+// again, reading the files added to it and dropping those removed, and Watch watches it.
+func (c Config) AddDirectory(dir string, pattern string, prefix string) error {
+	l, e := c.addProvider(DirectoryProvider{Dir: dir, Pattern: pattern}, prefix, true)
+	if e != nil {
+		return e
+	}
+	c.debugf("config: AddDirectory(%q, %q): read %d files", dir, pattern, len(l.parts))
+	return nil
+}
+
+// DirectoryProvider provides the values of the files in a directory, as AddDirectory reads
+// them. The directory is listed each time it is loaded.
+type DirectoryProvider struct {
+	Dir string
+	// Pattern selects the files, as for filepath.Match, e.g. "*.json". If it is empty, the
+	// files with an extension FormatForPath recognises are read.
+	Pattern string
+}
+
+func (p DirectoryProvider) Load() (map[string]interface{}, error) {
+	parts, e := p.parts("")
+	if e != nil {
+		return nil, e
+	}
+	values := make(map[string]interface{})
+	for _, l := range parts {
+		for k, v := range l.values {
+			values[k] = v
+		}
+	}
+	return values, nil
+}
+
+func (p DirectoryProvider) Describe() (string, string) {
+	return SourceFile, p.Dir
+}
+
+// parts reads each of the files, in lexical order, into a layer of its own, so that Explain
+// shows which file a value came from and arrays are merged between files.
+func (p DirectoryProvider) parts(prefix string) ([]*layer, error) {
+	entries, e := ioutil.ReadDir(p.Dir)
+	if e != nil {
+		return nil, e
+	}
+
+	var layers []*layer
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") {
+			continue
+		}
+		if p.Pattern == "" {
+			if !knownExtension(name) {
+				continue
+			}
+		} else if ok, e := filepath.Match(p.Pattern, name); e != nil {
+			return nil, e
+		} else if !ok {
+			continue
+		}
+
+		l, e := providerLayer(FileProvider{Path: filepath.Join(p.Dir, name)}, prefix, true).read()
+		if e != nil {
+			return nil, e
+		}
+		layers = append(layers, &l)
+	}
+	return layers, nil
+}
+
+// knownExtension reports whether name has the extension of one of the formats.
+func knownExtension(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json", ".jsonc", ".yaml", ".yml", ".toml", ".ini", ".cfg", ".properties":
+		return true
+	}
+	return false
+}
+
+// readFile reads path into values under prefix. If the file has an "$include" property,
+// the files it lists are read first, so the file's own settings override theirs. Relative
+// paths are relative to the including file, and may be patterns such as "conf.d/*.json",
+// whose matches are read in lexical order. The paths of included files are appended to
+// includes. chain holds the files being read, to detect loops.
+func readFile(path string, format string, prefix string, values map[string]interface{}, chain []string, includes *[]string) error {
+	abs, e := filepath.Abs(path)
+	if e != nil {
+		abs = path
+	}
+	for _, p := range chain {
+		if p == abs {
+			return fmt.Errorf("config: include loop: %s", strings.Join(append(chain, abs), " -> "))
+		}
+	}
+	chain = append(chain, abs)
+
+	data, e := ioutil.ReadFile(path)
+	if e != nil {
+		return e
+	}
+	nested, e := decode(data, format)
+	if e != nil {
+		if pe, ok := e.(*ParseError); ok {
+			pe.File = path
+			return pe
+		}
+		return fmt.Errorf("config: %s: %s", path, e)
+	}
+
+	if inc, ok := nested[includeKey]; ok {
+		delete(nested, includeKey)
+		paths, e := includePaths(inc, filepath.Dir(path))
+		if e != nil {
+			return fmt.Errorf("%s: %s", path, e)
+		}
+		for _, p := range paths {
+			*includes = append(*includes, p)
+			if e := readFile(p, FormatForPath(p), prefix, values, chain, includes); e != nil {
+				return e
+			}
+		}
+	}
+
+	flatten(nested, prefix, values)
+	return nil
+}
+
+// includePaths returns the paths listed by an "$include" property, which may be a list or
+// a single (possibly comma-separated) string, resolved relative to dir.
+func includePaths(v interface{}, dir string) ([]string, error) {
+	items, e := toSlice(v)
+	if e != nil {
+		return nil, fmt.Errorf("%s must be a list of paths", includeKey)
+	}
+
+	var paths []string
+	for _, item := range items {
+		p, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s must be a list of paths, not %T", includeKey, item)
+		}
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(dir, p)
+		}
+		if !strings.ContainsAny(p, "*?[") {
+			paths = append(paths, p)
+			continue
+		}
+		matches, e := filepath.Glob(p)
+		if e != nil {
+			return nil, e
+		}
+		sort.Strings(matches)
+		paths = append(paths, matches...)
+	}
+	return paths, nil
+}
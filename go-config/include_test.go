@@ -0,0 +1,144 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if e := ioutil.WriteFile(path, []byte(content), 0644); e != nil {
+			t.Fatal(e)
+		}
+	}
+}
+
+func TestAddDirectory(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"10-base.json":  `{"db": {"host": "base", "port": 5432}, "name": "base"}`,
+		"20-site.yaml":  "db:\n  host: site\n",
+		"30-local.json": `{"name": "local"}`,
+		".hidden.json":  `{"name": "hidden"}`,
+		"README":        "not config",
+		"sub/x.json":    `{"name": "sub"}`,
+	})
+
+	conf := NewConfig()
+	conf.AddDefault("db.user", "app")
+	if e := conf.AddDirectory(dir, "", "app"); e != nil {
+		t.Fatal(e)
+	}
+	expected := map[string]interface{}{
+		"db.user": "app", "app.db.host": "site", "app.db.port": 5432.0, "app.name": "local",
+	}
+	if !reflect.DeepEqual(conf.snapshot(), expected) {
+		t.Errorf("Expected %v, got %v", expected, conf.snapshot())
+	}
+
+	conf = NewConfig()
+	if e := conf.AddDirectory(dir, "*.json", ""); e != nil {
+		t.Fatal(e)
+	}
+	if conf.GetString("db.host") != "base" || conf.GetString("name") != "local" {
+		t.Errorf("Expected only the JSON files, got %v", conf.snapshot())
+	}
+
+	// nothing is added if a file is bad
+	writeFiles(t, dir, map[string]string{"40-bad.json": `{`})
+	conf = NewConfig()
+	if e := conf.AddDirectory(dir, "", ""); e == nil || len(conf.Keys()) != 0 {
+		t.Errorf("Expected an error and no keys, got %v, %v", e, conf.Keys())
+	}
+}
+
+func TestReloadDirectory(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"10-base.json": `{"name": "base", "tags": ["a"]}`,
+		"20-b.json":    `{"name": "b"}`,
+	})
+
+	conf := NewConfig()
+	conf.SetArrayMerge("tags", ArrayMerge{Strategy: MergeAppend})
+	if e := conf.AddDirectory(dir, "*.json", ""); e != nil {
+		t.Fatal(e)
+	}
+	if conf.GetString("name") != "b" {
+		t.Errorf("Expected name from 20-b.json, got %v", conf.Get("name"))
+	}
+
+	// a removed drop-in is dropped
+	os.Remove(filepath.Join(dir, "20-b.json"))
+	if changed, e := conf.Reload(); e != nil || !reflect.DeepEqual(changed, []string{"name"}) || conf.GetString("name") != "base" {
+		t.Errorf("Expected name to go back to base, got %v, %v, %v", changed, e, conf.Get("name"))
+	}
+
+	// and a new one is read, with arrays merged between files
+	writeFiles(t, dir, map[string]string{"30-c.json": `{"tags": ["c"]}`, "30-c.yaml": "name: yaml"})
+	if _, e := conf.Reload(); e != nil {
+		t.Fatal(e)
+	}
+	if tags := conf.GetStringArray("tags"); !reflect.DeepEqual(tags, []string{"a", "c"}) || conf.GetString("name") != "base" {
+		t.Errorf("Expected tags [a c] and name base, got %v and %v", tags, conf.Get("name"))
+	}
+	if e := conf.Explain("tags"); e.Source == nil || e.Source.Name != filepath.Join(dir, "30-c.json") || len(e.Merged) != 1 {
+		t.Errorf("Expected tags from 30-c.json merged with 10-base.json, got %s", e)
+	}
+
+	// the provider can also be used on its own
+	values, e := DirectoryProvider{Dir: dir}.Load()
+	if e != nil || values["name"] != "yaml" {
+		t.Errorf("Expected the files with known extensions, got %v, %v", values, e)
+	}
+}
+
+func TestInclude(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"app.json":           `{"$include": ["common/base.json", "conf.d/*.toml"], "name": "app"}`,
+		"common/base.json":   `{"$include": "db.yaml", "name": "base", "log": "info"}`,
+		"common/db.yaml":     "db:\n  host: db1\n",
+		"conf.d/10-db.toml":  "[db]\nhost = \"db2\"\n",
+		"conf.d/20-log.toml": "log = \"debug\"\n",
+		"loop/a.json":        `{"$include": ["b.json"]}`,
+		"loop/b.json":        `{"$include": ["a.json"]}`,
+		"missing.json":       `{"$include": ["nowhere.json"]}`,
+		"bad.json":           `{"$include": [1]}`,
+	})
+
+	conf := NewConfig()
+	if e := conf.AddFile(filepath.Join(dir, "app.json"), "", false); e != nil {
+		t.Fatal(e)
+	}
+	expected := map[string]interface{}{"name": "app", "log": "debug", "db.host": "db2"}
+	if !reflect.DeepEqual(conf.snapshot(), expected) {
+		t.Errorf("Expected %v, got %v", expected, conf.snapshot())
+	}
+	if files := conf.files(); len(files) != 5 {
+		t.Errorf("Expected the included files to be watched, got %v", files)
+	}
+
+	// included files are re-read by Reload
+	writeFiles(t, dir, map[string]string{"conf.d/20-log.toml": "log = \"warn\"\n"})
+	if changed, e := conf.Reload(); e != nil || !reflect.DeepEqual(changed, []string{"log"}) {
+		t.Errorf("Expected log to change, got %v, %v", changed, e)
+	}
+
+	e := NewConfig().AddFile(filepath.Join(dir, "loop/a.json"), "", false)
+	if e == nil || !strings.Contains(e.Error(), "include loop") || !strings.Contains(e.Error(), "a.json -> ") {
+		t.Errorf("Expected an include loop error, got %v", e)
+	}
+	if e := NewConfig().AddFile(filepath.Join(dir, "missing.json"), "", false); e == nil {
+		t.Errorf("Expected an error for a missing include")
+	}
+	if e := NewConfig().AddFile(filepath.Join(dir, "bad.json"), "", false); e == nil {
+		t.Errorf("Expected an error for an invalid include")
+	}
+}
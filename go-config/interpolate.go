@@ -0,0 +1,271 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// InterpolationError is returned when a value can't be expanded, because a reference
+// forms a cycle or names a key or environment variable that isn't set.
+type InterpolationError struct {
+	// Keys is the chain of keys being expanded, starting with the key that was read. For a
+	// cycle it ends with the key that was referenced again.
+	Keys []string
+	Err  error
+}
+
+func (e *InterpolationError) Error() string {
+	return fmt.Sprintf("config: %s: %s", strings.Join(e.Keys, " -> "), e.Err)
+}
+
+func (e *InterpolationError) Unwrap() error {
+	return e.Err
+}
+
+// value returns the value of key with any references in it expanded, and any secret it
+// refers to revealed. The error is an *InterpolationError, or a *KeyError wrapping
+// ErrNotFound if the key is not set.
+func (c Config) value(key string) (interface{}, error) {
+	return c.valueIn(c.snapshot(), key)
+}
+
+// valueIn is like value, but reads from the given snapshot.
+func (c Config) valueIn(values map[string]interface{}, key string) (interface{}, error) {
+	full := c.full(key)
+	v, ok := rawLookup(values, full)
+	if !ok {
+		return nil, &KeyError{Key: key, Err: ErrNotFound}
+	}
+	v, e := c.expand(values, v, []string{full})
+	if e != nil {
+		return nil, e
+	}
+	if v, e = c.revealSecret(v); e != nil {
+		return nil, &KeyError{Key: key, Err: e}
+	}
+	return v, nil
+}
+
+// rawLookup returns the stored value of key, following any array indexes in it.
+func rawLookup(values map[string]interface{}, key string) (interface{}, bool) {
+	if v, ok := values[key]; ok {
+		return v, true
+	}
+
+	base, steps, ok := parseKeyPath(key)
+	if !ok {
+		return nil, false
+	}
+	v, ok := values[base]
+	if !ok {
+		return nil, false
+	}
+	return resolvePath(v, steps)
+}
+
+// expand replaces the references in v, and in any strings within its arrays and objects.
+// Values without references are returned as they are; others are copied, so the stored
+// values are never modified. chain holds the keys being expanded, to detect cycles.
+//
+// A reference is one of
+//
+//	${other.key}           the value of another key (always from the top level, even in a view)
+//	${ENV:NAME}            the value of an environment variable, which must be set
+//	${ENV:NAME:-default}   the value of an environment variable, or default if it is unset or empty
+//
+// "$${" gives a literal "${". If a string is a single reference to another key, the
+// value keeps its type, so "${defaults.port}" can be read as an int; otherwise referenced
+// values are formatted into the string. A referenced key whose value is a secret reference
+// gives the secret (see EnableSecretRefs) in either case, so "postgres://app:${db.password}@db"
+// contains the password, not the "file://" URL it is read from.
+func (c Config) expand(values map[string]interface{}, v interface{}, chain []string) (interface{}, error) {
+	if !hasReference(v) {
+		return v, nil
+	}
+
+	switch vv := v.(type) {
+	case string:
+		return c.expandString(values, vv, chain)
+	case secretRef:
+		// e.g. "file://${secrets.dir}/password"
+		x, e := c.expandString(values, vv.ref, chain)
+		if e != nil {
+			return nil, e
+		}
+		return secretRef{kind: vv.kind, ref: fmt.Sprintf("%v", x)}, nil
+	case []interface{}:
+		result := make([]interface{}, len(vv))
+		for i, item := range vv {
+			x, e := c.expandItem(values, item, chain)
+			if e != nil {
+				return nil, e
+			}
+			result[i] = x
+		}
+		return result, nil
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(vv))
+		for k, item := range vv {
+			x, e := c.expandItem(values, item, chain)
+			if e != nil {
+				return nil, e
+			}
+			result[k] = x
+		}
+		return result, nil
+	}
+	return v, nil
+}
+
+// hasReference reports whether v is, or contains, a string with a reference in it.
+func hasReference(v interface{}) bool {
+	switch vv := v.(type) {
+	case string:
+		return strings.Contains(vv, "${")
+	case secretRef:
+		return strings.Contains(vv.ref, "${")
+	case []interface{}:
+		for _, item := range vv {
+			if hasReference(item) {
+				return true
+			}
+		}
+	case map[string]interface{}:
+		for _, item := range vv {
+			if hasReference(item) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// expandItem expands an element of an array or object. Only whole values are revealed
+// when read, so a secret it refers to is revealed here.
+func (c Config) expandItem(values map[string]interface{}, item interface{}, chain []string) (interface{}, error) {
+	x, e := c.expand(values, item, chain)
+	if e != nil {
+		return nil, e
+	}
+	if x, e = c.revealSecret(x); e != nil {
+		return nil, &InterpolationError{Keys: chain, Err: e}
+	}
+	return x, nil
+}
+
+func (c Config) expandString(values map[string]interface{}, s string, chain []string) (interface{}, error) {
+	var b strings.Builder
+	for {
+		i := strings.Index(s, "${")
+		if i < 0 {
+			b.WriteString(s)
+			break
+		}
+		if i > 0 && s[i-1] == '$' {
+			// "$${" is an escaped "${"
+			b.WriteString(s[:i-1])
+			b.WriteString("${")
+			s = s[i+2:]
+			continue
+		}
+		end := strings.IndexByte(s[i:], '}')
+		if end < 0 {
+			return nil, &InterpolationError{Keys: chain, Err: fmt.Errorf("unterminated reference in %q", s)}
+		}
+		ref := s[i+2 : i+end]
+
+		v, e := c.expandReference(values, ref, chain)
+		if e != nil {
+			return nil, e
+		}
+		if i == 0 && end == len(s)-1 && b.Len() == 0 {
+			// the whole string is one reference, revealed when it is read
+			return v, nil
+		}
+		if v, e = c.revealSecret(v); e != nil {
+			return nil, &InterpolationError{Keys: append(append([]string{}, chain...), strings.TrimSpace(ref)), Err: e}
+		}
+		b.WriteString(s[:i])
+		b.WriteString(fmt.Sprintf("%v", v))
+		s = s[i+end+1:]
+	}
+	return b.String(), nil
+}
+
+func (c Config) expandReference(values map[string]interface{}, ref string, chain []string) (interface{}, error) {
+	if strings.HasPrefix(ref, "ENV:") {
+		name, def, hasDefault := strings.Cut(ref[len("ENV:"):], ":-")
+		v, set := os.LookupEnv(name)
+		switch {
+		case hasDefault && v == "":
+			return def, nil
+		case !set:
+			return nil, &InterpolationError{Keys: chain, Err: fmt.Errorf("environment variable %s is not set", name)}
+		}
+		return v, nil
+	}
+
+	key := strings.TrimSpace(ref)
+	next := append(append([]string{}, chain...), key)
+	for _, k := range chain {
+		if k == key {
+			return nil, &InterpolationError{Keys: next, Err: errors.New("reference cycle")}
+		}
+	}
+	v, ok := rawLookup(values, key)
+	if !ok {
+		return nil, &InterpolationError{Keys: next, Err: fmt.Errorf("%s is not set", key)}
+	}
+	return c.expand(values, v, next)
+}
+
+// references returns the keys that v, or the strings within it, refer to. References to
+// environment variables are left out.
+func references(v interface{}) []string {
+	var keys []string
+	switch vv := v.(type) {
+	case string:
+		for s := vv; ; {
+			i := strings.Index(s, "${")
+			if i < 0 {
+				break
+			}
+			end := strings.IndexByte(s[i:], '}')
+			if end < 0 {
+				break
+			}
+			if ref := s[i+2 : i+end]; (i == 0 || s[i-1] != '$') && !strings.HasPrefix(ref, "ENV:") {
+				keys = append(keys, strings.TrimSpace(ref))
+			}
+			s = s[i+end+1:]
+		}
+	case secretRef:
+		keys = references(vv.ref)
+	case []interface{}:
+		for _, item := range vv {
+			keys = append(keys, references(item)...)
+		}
+	case map[string]interface{}:
+		for _, item := range vv {
+			keys = append(keys, references(item)...)
+		}
+	}
+	return keys
+}
+
+// CheckReferences expands every value in the config, and returns the errors for those
+// that can't be expanded, such as references to keys that aren't set and reference cycles.
+// It is useful after loading the configuration, since references are only expanded when
+// values are read.
+func (c Config) CheckReferences() []error {
+	values := c.snapshot()
+	var errs []error
+	for _, k := range c.Keys() {
+		if _, e := c.expand(values, values[c.full(k)], []string{c.full(k)}); e != nil {
+			errs = append(errs, e)
+		}
+	}
+	return errs
+}
@@ -0,0 +1,109 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func interpolationConfig() Config {
+	conf := NewConfig()
+	conf.nestedMerge(map[string]interface{}{
+		"base": map[string]interface{}{
+			"host": "example.com",
+			"port": 8080.0,
+			"dir":  "/srv/${app.name}",
+		},
+		"app": map[string]interface{}{
+			"name":  "shop",
+			"url":   "https://${base.host}:${base.port}/",
+			"port":  "${base.port}",
+			"logs":  "${base.dir}/logs",
+			"hosts": []interface{}{"${base.host}", "other.com"},
+			"home":  "${ENV:INTERPOLATETEST_HOME}",
+			"user":  "${ENV:INTERPOLATETEST_USER:-nobody}",
+			"raw":   "$${base.host}",
+		},
+	}, "", false)
+	return conf
+}
+
+func TestInterpolation(t *testing.T) {
+	os.Setenv("INTERPOLATETEST_HOME", "/home/shop")
+	defer os.Unsetenv("INTERPOLATETEST_HOME")
+	conf := interpolationConfig()
+
+	expected := map[string]string{
+		"app.url":  "https://example.com:8080/",
+		"app.logs": "/srv/shop/logs",
+		"app.home": "/home/shop",
+		"app.user": "nobody",
+		"app.raw":  "${base.host}",
+	}
+	for k, v := range expected {
+		if s, e := conf.GetStringE(k); e != nil || s != v {
+			t.Errorf("Expected %s to be '%s', got '%s', %v", k, v, s, e)
+		}
+	}
+
+	// a value that is just a reference keeps its type
+	if conf.Get("app.port") != 8080.0 || conf.GetInt("app.port") != 8080 {
+		t.Errorf("Expected app.port to be the number 8080, got %#v", conf.Get("app.port"))
+	}
+	if !reflect.DeepEqual(conf.GetStringArray("app.hosts"), []string{"example.com", "other.com"}) {
+		t.Errorf("Expected references within arrays to be expanded, got %v", conf.Get("app.hosts"))
+	}
+	if conf.Sub("app").GetString("logs") != "/srv/shop/logs" {
+		t.Errorf("Expected references from a view to be to top-level keys, got '%v'", conf.Sub("app").Get("logs"))
+	}
+
+	// references are resolved when read, so see later sources
+	conf.AddDefaultOverride("base.host", "example.org")
+	if conf.GetString("app.url") != "https://example.org:8080/" {
+		t.Errorf("Expected app.url to use the new base.host, got '%v'", conf.Get("app.url"))
+	}
+
+	type App struct {
+		URL  string `config:"url"`
+		Port int    `config:"port"`
+	}
+	var app App
+	if e := conf.Unmarshal("app", &app); e != nil || app.URL != "https://example.org:8080/" || app.Port != 8080 {
+		t.Errorf("Expected Unmarshal to expand references, got %+v, %v", app, e)
+	}
+}
+
+func TestInterpolationErrors(t *testing.T) {
+	os.Unsetenv("INTERPOLATETEST_HOME")
+	conf := interpolationConfig()
+	conf.AddDefault("cycle.a", "${cycle.b}")
+	conf.AddDefault("cycle.b", "x${cycle.c}")
+	conf.AddDefault("cycle.c", "${cycle.a}")
+	conf.AddDefault("dangling", "${nowhere}")
+
+	_, e := conf.GetStringE("cycle.a")
+	var ie *InterpolationError
+	if !errors.As(e, &ie) || !reflect.DeepEqual(ie.Keys, []string{"cycle.a", "cycle.b", "cycle.c", "cycle.a"}) {
+		t.Fatalf("Expected a cycle through cycle.a, cycle.b and cycle.c, got %v", e)
+	}
+	if !strings.Contains(e.Error(), "cycle.a -> cycle.b -> cycle.c -> cycle.a") {
+		t.Errorf("Expected the error to name the keys, got %v", e)
+	}
+	if conf.Get("cycle.a") != nil {
+		t.Errorf("Expected Get to return nil for a cycle, got %v", conf.Get("cycle.a"))
+	}
+
+	_, e = conf.GetIntE("dangling")
+	if !errors.As(e, &ie) || errors.Is(e, ErrNotFound) || !strings.Contains(e.Error(), "dangling -> nowhere") {
+		t.Errorf("Expected an error naming dangling and nowhere, got %v", e)
+	}
+	if _, e := conf.GetStringE("app.home"); !errors.As(e, &ie) || !strings.Contains(e.Error(), "INTERPOLATETEST_HOME") {
+		t.Errorf("Expected an error naming the unset variable, got %v", e)
+	}
+
+	if errs := conf.CheckReferences(); len(errs) != 5 {
+		t.Errorf("Expected 5 errors (app.home, the 3 cycle keys and dangling), got %v", errs)
+	}
+}
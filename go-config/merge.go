@@ -0,0 +1,239 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Strategies for combining an array with the array set for the same key by another source.
+const (
+	// MergeReplace keeps only the array of the source that takes precedence. It is the
+	// default, and was the only behaviour originally.
+	MergeReplace = "replace"
+	// MergeAppend puts the elements of the source that takes precedence after the others.
+	MergeAppend = "append"
+	// MergePrepend puts them before the others.
+	MergePrepend = "prepend"
+	// MergeUnion appends, leaving out duplicates.
+	MergeUnion = "union"
+	// MergeByKey merges arrays of objects, matching up elements whose field ArrayMerge.Key
+	// has the same value. Matching objects are merged, with the fields of the source that
+	// takes precedence winning, and the rest are appended.
+	MergeByKey = "key"
+)
+
+// ArrayMerge says how an array is combined with an array for the same key from another
+// source. Of the two sources, the one that takes precedence is the later one if it was
+// added with override, and the earlier one otherwise.
+type ArrayMerge struct {
+	// Strategy is one of the Merge* constants. "" is the same as MergeReplace.
+	Strategy string
+	// Key is the field that identifies the objects in the arrays, for MergeByKey.
+	Key string
+}
+
+func (m ArrayMerge) check() error {
+	switch m.Strategy {
+	case "", MergeReplace, MergeAppend, MergePrepend, MergeUnion:
+		return nil
+	case MergeByKey:
+		if m.Key == "" {
+			return fmt.Errorf("config: array merge strategy %q needs a Key", m.Strategy)
+		}
+		return nil
+	}
+	return fmt.Errorf("config: unknown array merge strategy %q", m.Strategy)
+}
+
+// arrayMerges holds the strategies set with SetArrayMerge and SetDefaultArrayMerge. The
+// map is replaced, not modified, when a strategy is set, so copies can share it.
+type arrayMerges struct {
+	def  ArrayMerge
+	keys map[string]ArrayMerge
+}
+
+// SetArrayMerge sets how the arrays set for key by different sources are combined, so that,
+// for instance, with MergeAppend each drop-in file of AddDirectory can add to a list of
+// allowed origins instead of replacing it:
+//
+//	conf.SetArrayMerge("cors.allowedOrigins", config.ArrayMerge{Strategy: config.MergeAppend})
+//	conf.SetArrayMerge("servers", config.ArrayMerge{Strategy: config.MergeByKey, Key: "name"})
+//
+// The config is rebuilt from its sources with the new strategy, which is also used by later
+// sources and by Reload, so it can be set before or after the sources are added. As with
+// Reload, if the rebuilt config doesn't match the schema set with SetSchema, the strategy
+// isn't set and the error is returned; otherwise the listeners registered with OnChange are
+// called with the keys that changed.
+func (c Config) SetArrayMerge(key string, m ArrayMerge) error {
+	if e := m.check(); e != nil {
+		return e
+	}
+
+	c.s.mu.Lock()
+	keys := make(map[string]ArrayMerge, len(c.s.arrayMerges.keys)+1)
+	for k, km := range c.s.arrayMerges.keys {
+		keys[k] = km
+	}
+	keys[c.full(key)] = m
+	changed, listeners, e := c.s.rebuild(arrayMerges{def: c.s.arrayMerges.def, keys: keys})
+	c.s.mu.Unlock()
+
+	if e != nil {
+		return e
+	}
+	notify(listeners, changed)
+	return nil
+}
+
+// SetDefaultArrayMerge sets how arrays are combined for keys without a strategy set by
+// SetArrayMerge. The config is rebuilt as for SetArrayMerge.
+func (c Config) SetDefaultArrayMerge(m ArrayMerge) error {
+	if e := m.check(); e != nil {
+		return e
+	}
+
+	c.s.mu.Lock()
+	changed, listeners, e := c.s.rebuild(arrayMerges{def: m, keys: c.s.arrayMerges.keys})
+	c.s.mu.Unlock()
+
+	if e != nil {
+		return e
+	}
+	notify(listeners, changed)
+	return nil
+}
+
+// rebuild merges the values of the layers again, as they are, using merges. If the result
+// matches the schema, merges and the values replace those of the store, and the changed
+// keys are returned with the listeners to call once the lock is released. The caller holds
+// the lock.
+func (s *store) rebuild(merges arrayMerges) ([]string, []func(changed []string), error) {
+	values := make(map[string]interface{})
+	for _, l := range s.layers {
+		l.apply(values, merges)
+	}
+	if e := s.validateValues(values, s.layers); e != nil {
+		return nil, nil, e
+	}
+
+	changed := changedKeys(*s.values.Load(), values)
+	s.arrayMerges = merges
+	s.values.Store(&values)
+	return changed, append([]func(changed []string){}, s.listeners...), nil
+}
+
+// merge combines the values lower and higher (that of the source that takes precedence)
+// for key, if they are both arrays and key has a strategy other than MergeReplace. It
+// returns false if higher simply replaces lower.
+func (a arrayMerges) merge(key string, lower, higher interface{}) (interface{}, bool) {
+	m, ok := a.keys[key]
+	if !ok {
+		m = a.def
+	}
+	if m.Strategy == "" || m.Strategy == MergeReplace {
+		return nil, false
+	}
+	lowerItems, ok := asArray(lower)
+	if !ok {
+		return nil, false
+	}
+	higherItems, ok := asArray(higher)
+	if !ok {
+		return nil, false
+	}
+	return mergeArrays(lowerItems, higherItems, m), true
+}
+
+// asArray returns v as a []interface{} if it is an array of any type, such as the []string
+// stored by AddDefaultStringArray.
+func asArray(v interface{}) ([]interface{}, bool) {
+	if v == nil {
+		return nil, false
+	}
+	if k := reflect.TypeOf(v).Kind(); k != reflect.Slice && k != reflect.Array {
+		return nil, false
+	}
+	items, e := toSlice(v)
+	return items, e == nil
+}
+
+// mergeArrays combines lower and higher, the array of the source that takes precedence,
+// using m. The arrays are not modified.
+func mergeArrays(lower, higher []interface{}, m ArrayMerge) []interface{} {
+	result := make([]interface{}, 0, len(lower)+len(higher))
+	switch m.Strategy {
+	case MergeAppend:
+		result = append(append(result, lower...), higher...)
+	case MergePrepend:
+		result = append(append(result, higher...), lower...)
+	case MergeUnion:
+		for _, item := range append(append(result, lower...), higher...) {
+			if indexOf(result, item) < 0 {
+				result = append(result, item)
+			}
+		}
+	case MergeByKey:
+		result = append(result, lower...)
+		for _, item := range higher {
+			i := indexByKey(result, item, m.Key)
+			if i < 0 {
+				result = append(result, item)
+				continue
+			}
+			result[i] = mergeObjects(result[i].(map[string]interface{}), item.(map[string]interface{}))
+		}
+	default:
+		result = append(result, higher...)
+	}
+	return result
+}
+
+// indexOf returns the index of the first element of items equal to item, or -1.
+func indexOf(items []interface{}, item interface{}) int {
+	for i, x := range items {
+		if reflect.DeepEqual(x, item) {
+			return i
+		}
+	}
+	return -1
+}
+
+// indexByKey returns the index of the first object in items whose field key has the same
+// value as that of item, or -1, including if item isn't an object or hasn't the field.
+func indexByKey(items []interface{}, item interface{}, key string) int {
+	object, ok := item.(map[string]interface{})
+	if !ok {
+		return -1
+	}
+	id, ok := object[key]
+	if !ok {
+		return -1
+	}
+	for i, x := range items {
+		if xo, ok := x.(map[string]interface{}); ok {
+			if xid, ok := xo[key]; ok && reflect.DeepEqual(xid, id) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// mergeObjects returns a copy of lower with the fields of higher merged in. Nested objects
+// are merged in the same way; any other field of higher replaces that of lower.
+func mergeObjects(lower, higher map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(lower)+len(higher))
+	for k, v := range lower {
+		result[k] = v
+	}
+	for k, v := range higher {
+		lo, lok := result[k].(map[string]interface{})
+		ho, hok := v.(map[string]interface{})
+		if lok && hok {
+			result[k] = mergeObjects(lo, ho)
+		} else {
+			result[k] = v
+		}
+	}
+	return result
+}
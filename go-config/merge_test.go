@@ -0,0 +1,160 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestArrayMerge(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"10-base.json": `{"cors": {"allowedOrigins": ["https://a.example", "https://b.example"]}, "tags": ["x"]}`,
+		"20-site.json": `{"cors": {"allowedOrigins": ["https://b.example", "https://c.example"]}, "tags": ["y"]}`,
+	})
+
+	for strategy, expected := range map[string][]string{
+		"":           {"https://b.example", "https://c.example"},
+		MergeReplace: {"https://b.example", "https://c.example"},
+		MergeAppend:  {"https://a.example", "https://b.example", "https://b.example", "https://c.example"},
+		MergePrepend: {"https://b.example", "https://c.example", "https://a.example", "https://b.example"},
+		MergeUnion:   {"https://a.example", "https://b.example", "https://c.example"},
+	} {
+		conf := NewConfig()
+		if e := conf.SetArrayMerge("cors.allowedOrigins", ArrayMerge{Strategy: strategy}); e != nil {
+			t.Fatal(e)
+		}
+		if e := conf.AddDirectory(dir, "*.json", ""); e != nil {
+			t.Fatal(e)
+		}
+		if origins := conf.GetStringArray("cors.allowedOrigins"); !reflect.DeepEqual(origins, expected) {
+			t.Errorf("%q: Expected %v, got %v", strategy, expected, origins)
+		}
+		// other keys are still replaced
+		if tags := conf.GetStringArray("tags"); !reflect.DeepEqual(tags, []string{"y"}) {
+			t.Errorf("%q: Expected tags to be replaced, got %v", strategy, tags)
+		}
+	}
+
+	// without override, the existing array takes precedence
+	conf := NewConfig()
+	conf.SetDefaultArrayMerge(ArrayMerge{Strategy: MergeAppend})
+	conf.AddDefaultStringArrayOverride("list", []string{"a"})
+	conf.AddDefaultStringArray("list", []string{"b"})
+	if list := conf.GetStringArray("list"); !reflect.DeepEqual(list, []string{"b", "a"}) {
+		t.Errorf("Expected [b a], got %v", list)
+	}
+
+	if e := conf.SetArrayMerge("list", ArrayMerge{Strategy: "concat"}); e == nil {
+		t.Errorf("Expected an error for an unknown strategy")
+	}
+	if e := conf.SetArrayMerge("list", ArrayMerge{Strategy: MergeByKey}); e == nil {
+		t.Errorf("Expected an error for MergeByKey without a key")
+	}
+}
+
+func TestArrayMergeByKey(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"10-base.json": `{"servers": [
+			{"name": "a", "port": 80, "tls": {"enabled": false, "cert": "a.pem"}},
+			{"name": "b", "port": 81},
+			"unnamed"
+		]}`,
+		"20-site.json": `{"servers": [
+			{"name": "a", "port": 8080, "tls": {"enabled": true}},
+			{"name": "c", "port": 82}
+		]}`,
+	})
+
+	conf := NewConfig()
+	if e := conf.AddDirectory(dir, "", ""); e != nil {
+		t.Fatal(e)
+	}
+	// setting the strategy afterwards rebuilds the config
+	if e := conf.SetArrayMerge("servers", ArrayMerge{Strategy: MergeByKey, Key: "name"}); e != nil {
+		t.Fatal(e)
+	}
+
+	if n := conf.Len("servers"); n != 4 {
+		t.Fatalf("Expected 4 servers, got %d: %v", n, conf.Get("servers"))
+	}
+	for key, expected := range map[string]interface{}{
+		"servers[0].port":        float64(8080),
+		"servers[0].tls.enabled": true,
+		"servers[0].tls.cert":    "a.pem",
+		"servers[1].name":        "b",
+		"servers[2]":             "unnamed",
+		"servers[3].name":        "c",
+	} {
+		if v := conf.Get(key); v != expected {
+			t.Errorf("Expected %s to be %v, got %v", key, expected, v)
+		}
+	}
+
+	// Explain shows both files
+	e := conf.Explain("servers")
+	if e.Source == nil || !strings.HasSuffix(e.Source.Name, "20-site.json") || len(e.Merged) != 1 || len(e.Shadowed) != 0 {
+		t.Errorf("Expected servers from 20-site.json merged with 10-base.json, got %s", e)
+	}
+	if !reflect.DeepEqual(e.Value, conf.Get("servers")) {
+		t.Errorf("Expected the explained value to be the merged one, got %v", e.Value)
+	}
+
+	// and Reload merges again
+	writeFiles(t, dir, map[string]string{
+		"20-site.json": `{"servers": [{"name": "b", "port": 9090}]}`,
+	})
+	if _, e := conf.Reload(); e != nil {
+		t.Fatal(e)
+	}
+	if conf.Len("servers") != 3 || conf.GetInt("servers[0].port") != 80 || conf.GetInt("servers[1].port") != 9090 {
+		t.Errorf("Expected the reloaded servers to be merged, got %v", conf.Get("servers"))
+	}
+}
+
+func TestArrayMergeValidatesAndNotifies(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"10-base.json": `{"servers": ["a", "b"], "tags": ["x"]}`,
+		"20-site.json": `{"servers": ["c"], "tags": ["y"]}`,
+	})
+
+	conf := NewConfig()
+	if e := conf.AddDirectory(dir, "", ""); e != nil {
+		t.Fatal(e)
+	}
+	schema := &Schema{Properties: map[string]*Schema{"servers": {Type: Types("array"), MaxItems: Int(2)}}}
+	if e := conf.SetSchema(schema); e != nil {
+		t.Fatal(e)
+	}
+	var changes [][]string
+	conf.OnChange(func(changed []string) {
+		changes = append(changes, changed)
+	})
+
+	// a strategy giving an invalid config is rejected, and the config is unchanged
+	if e := conf.SetArrayMerge("servers", ArrayMerge{Strategy: MergeAppend}); len(violations(e)) != 1 {
+		t.Errorf("Expected the merged servers to be rejected, got %v", e)
+	}
+	if servers := conf.GetStringArray("servers"); !reflect.DeepEqual(servers, []string{"c"}) || len(changes) != 0 {
+		t.Errorf("Expected servers to be unchanged, got %v after %v", servers, changes)
+	}
+	if _, e := conf.Reload(); e != nil || conf.Len("servers") != 1 {
+		t.Errorf("Expected Reload to still replace servers, got %v, %v", e, conf.Get("servers"))
+	}
+
+	// listeners are only told of changes
+	if e := conf.SetArrayMerge("servers", ArrayMerge{Strategy: MergeReplace}); e != nil || len(changes) != 0 {
+		t.Errorf("Expected no changes, got %v, %v", e, changes)
+	}
+	if e := conf.SetDefaultArrayMerge(ArrayMerge{Strategy: MergeUnion}); e != nil {
+		t.Fatal(e)
+	}
+	if !reflect.DeepEqual(changes, [][]string{{"tags"}}) {
+		t.Errorf("Expected listeners to be called with [tags], got %v", changes)
+	}
+	if conf.Len("servers") != 1 || conf.Len("tags") != 2 {
+		t.Errorf("Expected the union of the tags only, got %v and %v", conf.Get("servers"), conf.Get("tags"))
+	}
+}
@@ -0,0 +1,111 @@
+package config
+
+import (
+	"strconv"
+	"strings"
+)
+
+// pathStep is one step of a key into an array value: an index, a wildcard, or a
+// property of an object within an array.
+type pathStep struct {
+	field    string
+	index    int
+	wildcard bool
+}
+
+// parseKeyPath splits a key such as "servers[0].host" into the flattened key holding the
+// array ("servers") and the steps into it. ok is false if the key has no index or is
+// malformed.
+func parseKeyPath(key string) (base string, steps []pathStep, ok bool) {
+	i := strings.IndexByte(key, '[')
+	if i <= 0 {
+		return "", nil, false
+	}
+	base, rest := key[:i], key[i:]
+
+	for rest != "" {
+		switch rest[0] {
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return "", nil, false
+			}
+			inner := strings.TrimSpace(rest[1:end])
+			if inner == "*" {
+				steps = append(steps, pathStep{wildcard: true})
+			} else {
+				n, e := strconv.Atoi(inner)
+				if e != nil || n < 0 {
+					return "", nil, false
+				}
+				steps = append(steps, pathStep{index: n})
+			}
+			rest = rest[end+1:]
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return "", nil, false
+			}
+			steps = append(steps, pathStep{field: rest[:end]})
+			rest = rest[end:]
+		default:
+			return "", nil, false
+		}
+	}
+	return base, steps, true
+}
+
+// resolvePath follows steps into v. A wildcard step applies the remaining steps to every
+// element of an array, and collects the results of those that succeed into a list.
+func resolvePath(v interface{}, steps []pathStep) (interface{}, bool) {
+	if len(steps) == 0 {
+		return v, true
+	}
+	step := steps[0]
+
+	if step.field != "" {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		child, ok := m[step.field]
+		if !ok {
+			return nil, false
+		}
+		return resolvePath(child, steps[1:])
+	}
+
+	// any type of array, such as the []string stored by AddDefaultStringArray
+	a, ok := asArray(v)
+	if !ok {
+		return nil, false
+	}
+	if step.wildcard {
+		result := make([]interface{}, 0, len(a))
+		for _, item := range a {
+			if r, ok := resolvePath(item, steps[1:]); ok {
+				result = append(result, r)
+			}
+		}
+		return result, true
+	}
+	if step.index >= len(a) {
+		return nil, false
+	}
+	return resolvePath(a[step.index], steps[1:])
+}
+
+// Len returns the length of the array at key, which may itself use index syntax, e.g.
+// Len("servers") or Len("servers[0].aliases"). It returns 0 if the key is not set or
+// is not an array.
+func (c Config) Len(key string) int {
+	a, ok := asArray(c.Get(key))
+	if !ok {
+		return 0
+	}
+	return len(a)
+}
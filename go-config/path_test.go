@@ -0,0 +1,127 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const serversTOML = `
+[[servers]]
+host = "a.example.com"
+port = 8080
+aliases = ["a1", "a2"]
+
+[[servers]]
+host = "b.example.com"
+port = 8081
+`
+
+func readServers(t *testing.T) Config {
+	path := filepath.Join(t.TempDir(), "servers.toml")
+	if e := os.WriteFile(path, []byte(serversTOML), 0644); e != nil {
+		t.Fatal(e)
+	}
+	conf, e := ReadFromFile(path)
+	if e != nil {
+		t.Fatal(e)
+	}
+	return conf
+}
+
+func TestIndexKeys(t *testing.T) {
+	conf := readServers(t)
+
+	if conf.GetString("servers[1].host") != "b.example.com" {
+		t.Errorf("Expected servers[1].host to be b.example.com, got '%v'", conf.Get("servers[1].host"))
+	}
+	if conf.GetInt("servers[0].port") != 8080 {
+		t.Errorf("Expected servers[0].port to be 8080, got '%v'", conf.Get("servers[0].port"))
+	}
+	if conf.GetString("servers[0].aliases[1]") != "a2" {
+		t.Errorf("Expected servers[0].aliases[1] to be a2, got '%v'", conf.Get("servers[0].aliases[1]"))
+	}
+	for _, key := range []string{"servers[2].host", "servers[0].missing", "servers[x]", "servers[0", "servers[0].host[0]"} {
+		if conf.HasKey(key) {
+			t.Errorf("Expected %s not to be set, got '%v'", key, conf.Get(key))
+		}
+	}
+
+	// the original test.json has a plain array
+	json, _ := ReadFromFile("./test.json")
+	if json.Get("test1.arrayprop[2]") == nil {
+		t.Errorf("Expected test1.arrayprop[2] to be set")
+	}
+}
+
+func TestWildcardKeys(t *testing.T) {
+	conf := readServers(t)
+
+	hosts := conf.GetStringArray("servers[*].host")
+	if !reflect.DeepEqual(hosts, []string{"a.example.com", "b.example.com"}) {
+		t.Errorf("Expected both hosts, got %v", hosts)
+	}
+	// servers without aliases are skipped
+	aliases := conf.Get("servers[*].aliases[0]")
+	if !reflect.DeepEqual(aliases, []interface{}{"a1"}) {
+		t.Errorf("Expected [a1], got %v", aliases)
+	}
+}
+
+func TestLen(t *testing.T) {
+	conf := readServers(t)
+
+	if conf.Len("servers") != 2 {
+		t.Errorf("Expected 2 servers, got %d", conf.Len("servers"))
+	}
+	if conf.Len("servers[0].aliases") != 2 {
+		t.Errorf("Expected 2 aliases, got %d", conf.Len("servers[0].aliases"))
+	}
+	if conf.Len("servers[0].host") != 0 || conf.Len("missing") != 0 {
+		t.Errorf("Expected non-arrays to have length 0")
+	}
+}
+
+func TestStringArrayPaths(t *testing.T) {
+	conf := NewConfig()
+	conf.AddDefaultStringArray("origins", []string{"https://a.example", "https://b.example"})
+
+	if conf.Len("origins") != 2 {
+		t.Errorf("Expected 2 origins, got %d", conf.Len("origins"))
+	}
+	if conf.GetString("origins[1]") != "https://b.example" || conf.HasKey("origins[2]") {
+		t.Errorf("Expected origins[1] to be set and origins[2] not, got %v", conf.Get("origins[1]"))
+	}
+	if all := conf.GetStringArray("origins[*]"); !reflect.DeepEqual(all, []string{"https://a.example", "https://b.example"}) {
+		t.Errorf("Expected both origins, got %v", all)
+	}
+}
+
+func TestIndexKeysIntoStructs(t *testing.T) {
+	conf := readServers(t)
+
+	type Server struct {
+		Host    string   `config:"host"`
+		Port    int      `config:"port" required:"true"`
+		Aliases []string `config:"aliases"`
+	}
+	servers, e := Get[[]Server](conf, "servers")
+	if e != nil {
+		t.Fatal(e)
+	}
+	expected := []Server{{"a.example.com", 8080, []string{"a1", "a2"}}, {"b.example.com", 8081, nil}}
+	if !reflect.DeepEqual(servers, expected) {
+		t.Errorf("Expected %v, got %v", expected, servers)
+	}
+
+	if first, e := Get[Server](conf.Sub("servers[0]"), ""); e != nil || first.Host != "a.example.com" {
+		t.Errorf("Expected the view of servers[0] to be read into a struct, got %v, %v", first, e)
+	}
+	if s, e := Get[Server](conf, "servers[1]"); e != nil || s.Port != 8081 {
+		t.Errorf("Expected servers[1] to be read into a struct, got %v, %v", s, e)
+	}
+	if port := conf.Sub("servers[1]").GetInt("port"); port != 8081 {
+		t.Errorf("Expected a view of servers[1] to read port 8081, got %d", port)
+	}
+}
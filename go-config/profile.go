@@ -0,0 +1,110 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ProfileOptions controls how AddProfile finds the files of a profile.
+type ProfileOptions struct {
+	// Name is the base name of the files, e.g. "config" for config.json,
+	// config.staging.json and config.local.json.
+	Name string
+	// SearchPaths are the directories searched, in order, e.g. "/etc/app" then ".". Files
+	// found in later directories override those in earlier ones. It defaults to ".".
+	SearchPaths []string
+	// Profile is the active profile, e.g. "staging". If it is empty, it is read from the
+	// environment variable named by ProfileEnv, e.g. "APP_ENV"; if that is also empty,
+	// only the base and local files are loaded.
+	Profile    string
+	ProfileEnv string
+	// Local is the suffix of the local overrides, which are loaded last. It defaults to
+	// "local", for config.local.json.
+	Local string
+	// Extensions are the extensions tried for each file, in order; the first that exists
+	// in a directory is used. They default to json, yaml, yml and toml.
+	Extensions []string
+	// DestPrefix places the keys within a namespace of the Config, as for AddFile.
+	DestPrefix string
+}
+
+// ProfileResult reports what AddProfile loaded.
+type ProfileResult struct {
+	// Profile is the active profile, "" if there is none.
+	Profile string
+	// Files are the files that were found and added, in the order they were added.
+	Files []string
+}
+
+// AddProfile adds the base file of a profile, then the overlay for the active profile,
+// then the local overrides, searching each directory of opts.SearchPaths in turn. With
+// Name "config" and profile "prod", that is config.json, then config.prod.json, then
+// config.local.json. Each file overrides those before it and any settings already added,
+// so add the environment and flags afterwards. The base file must exist in at least one
+// directory; the others are optional. The files are all read before any are added, so if
+// one can't be read the config is left unchanged.
+func (c Config) AddProfile(opts ProfileOptions) (ProfileResult, error) {
+	result := ProfileResult{Profile: opts.Profile}
+	if result.Profile == "" && opts.ProfileEnv != "" {
+		result.Profile = os.Getenv(opts.ProfileEnv)
+	}
+	if strings.ContainsAny(result.Profile, `/\`) {
+		return result, fmt.Errorf("config: invalid profile %q", result.Profile)
+	}
+	if len(opts.SearchPaths) == 0 {
+		opts.SearchPaths = []string{"."}
+	}
+	if opts.Local == "" {
+		opts.Local = "local"
+	}
+	if len(opts.Extensions) == 0 {
+		opts.Extensions = []string{"json", "yaml", "yml", "toml"}
+	}
+
+	names := []string{opts.Name}
+	if result.Profile != "" {
+		names = append(names, opts.Name+"."+result.Profile)
+	}
+	names = append(names, opts.Name+"."+opts.Local)
+
+	var layers []*layer
+	for i, name := range names {
+		found := false
+		for _, dir := range opts.SearchPaths {
+			path, ok := findFile(dir, name, opts.Extensions)
+			if !ok {
+				continue
+			}
+			found = true
+			l, e := providerLayer(FileProvider{Path: path}, c.full(opts.DestPrefix), true).read()
+			if e != nil {
+				return result, e
+			}
+			layers = append(layers, &l)
+			result.Files = append(result.Files, path)
+		}
+		if i == 0 && !found {
+			return result, fmt.Errorf("config: no %s file (%s) found in %s", name,
+				strings.Join(opts.Extensions, ", "), strings.Join(opts.SearchPaths, ", "))
+		}
+	}
+
+	for _, l := range layers {
+		c.add(l)
+	}
+	c.debugf("config: AddProfile(%q, %q): read %s", opts.Name, result.Profile, result.Files)
+	return result, nil
+}
+
+// findFile returns the path of the first file in dir named name with one of extensions.
+func findFile(dir string, name string, extensions []string) (string, bool) {
+	for _, ext := range extensions {
+		path := filepath.Join(dir, name+"."+strings.TrimPrefix(ext, "."))
+		if info, e := os.Stat(path); e == nil && !info.IsDir() {
+			return path, true
+		}
+	}
+	return "", false
+}
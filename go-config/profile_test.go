@@ -0,0 +1,58 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAddProfile(t *testing.T) {
+	etc, dir := t.TempDir(), t.TempDir()
+	writeFiles(t, etc, map[string]string{
+		"config.json":      `{"db": {"host": "etc", "port": 5432}, "log": "info"}`,
+		"config.prod.yaml": "log: warn\n",
+	})
+	writeFiles(t, dir, map[string]string{
+		"config.json":       `{"db": {"host": "base"}}`,
+		"config.prod.json":  `{"db": {"host": "prod"}}`,
+		"config.local.toml": "log = \"debug\"\n",
+		"config.dev.json":   `{"db": {"host": "dev"}}`,
+	})
+
+	os.Setenv("PROFILETEST_ENV", "prod")
+	defer os.Unsetenv("PROFILETEST_ENV")
+
+	conf := NewConfig()
+	result, e := conf.AddProfile(ProfileOptions{Name: "config", SearchPaths: []string{etc, dir}, ProfileEnv: "PROFILETEST_ENV"})
+	if e != nil {
+		t.Fatal(e)
+	}
+	files := []string{
+		filepath.Join(etc, "config.json"), filepath.Join(dir, "config.json"),
+		filepath.Join(etc, "config.prod.yaml"), filepath.Join(dir, "config.prod.json"),
+		filepath.Join(dir, "config.local.toml"),
+	}
+	if result.Profile != "prod" || !reflect.DeepEqual(result.Files, files) {
+		t.Errorf("Expected profile prod from %v, got %+v", files, result)
+	}
+	expected := map[string]interface{}{"db.host": "prod", "db.port": 5432.0, "log": "debug"}
+	if !reflect.DeepEqual(conf.snapshot(), expected) {
+		t.Errorf("Expected %v, got %v", expected, conf.snapshot())
+	}
+
+	// an explicit profile wins over the environment, and a missing overlay is fine
+	conf = NewConfig()
+	result, e = conf.AddProfile(ProfileOptions{Name: "config", SearchPaths: []string{etc}, Profile: "test", ProfileEnv: "PROFILETEST_ENV"})
+	if e != nil || result.Profile != "test" || len(result.Files) != 1 || conf.GetString("log") != "info" {
+		t.Errorf("Expected only the base file, got %+v, %v", result, e)
+	}
+
+	if _, e := NewConfig().AddProfile(ProfileOptions{Name: "missing", SearchPaths: []string{etc, dir}}); e == nil || !strings.Contains(e.Error(), "no missing file") {
+		t.Errorf("Expected an error for a missing base file, got %v", e)
+	}
+	if _, e := NewConfig().AddProfile(ProfileOptions{Name: "config", SearchPaths: []string{dir}, Profile: "../x"}); e == nil {
+		t.Errorf("Expected an error for an invalid profile")
+	}
+}
@@ -0,0 +1,238 @@
+package config
+
+import (
+	"flag"
+
+	"github.com/spf13/pflag"
+)
+
+// SourceProvider is the kind of source for values from a Provider that doesn't describe
+// itself.
+const SourceProvider = "provider"
+
+// Provider is a source of configuration values, added to a Config with AddProvider. The
+// package includes providers for files, the environment, flags, defaults and HTTP
+// endpoints; others, such as a key-value store, can be added by implementing Load.
+type Provider interface {
+	// Load returns the values of the source, as nested objects (as decoded from JSON),
+	// dot-delimited keys, or a mix of both. It is called again by Reload.
+	Load() (map[string]interface{}, error)
+}
+
+// WatchableProvider is a Provider that can tell when its values change. A Watcher
+// created by Config.Watch calls Watch for each one, and reloads the config when it calls
+// changed.
+type WatchableProvider interface {
+	Provider
+	// Watch calls changed each time the values of the provider may have changed, and
+	// onError with errors that don't stop it watching. It returns when stop is closed.
+	Watch(stop <-chan struct{}, changed func(), onError func(error))
+}
+
+// DescribedProvider is a Provider that names the kind of source it is (one of the
+// Source* constants) and the source itself, for Explain. Others are described as
+// SourceProvider.
+type DescribedProvider interface {
+	Provider
+	Describe() (kind string, name string)
+}
+
+// AddProvider loads the values of p and merges them into the config, under destPrefix
+// and with the given override setting, as for AddFile. Reload loads them again.
+func (c Config) AddProvider(p Provider, destPrefix string, override bool) error {
+	l, e := c.addProvider(p, destPrefix, override)
+	if e != nil {
+		return e
+	}
+	c.debugf("config: AddProvider(%s %s): read %d keys", l.kind, l.name, len(l.values))
+
+	return nil
+}
+
+// addProvider loads the values of p into a new layer, and adds it. AddFile and the other
+// built-in sources that can be read again are added this way too.
+func (c Config) addProvider(p Provider, destPrefix string, override bool) (*layer, error) {
+	l, e := providerLayer(p, c.full(destPrefix), override).read()
+	if e != nil {
+		return nil, e
+	}
+	c.add(&l)
+	return &l, nil
+}
+
+// providerLayer returns a layer, without values yet, for the values of p under prefix.
+func providerLayer(p Provider, prefix string, override bool) *layer {
+	l := &layer{kind: SourceProvider, prefix: prefix, override: override, provider: p}
+	if d, ok := p.(DescribedProvider); ok {
+		l.kind, l.name = d.Describe()
+	}
+	return l
+}
+
+// sourceProvider is implemented by the providers of this package, which know more about
+// their values than Load returns: the variable or flag each key came from, and the files
+// included by a file, which are watched along with it.
+type sourceProvider interface {
+	Provider
+	load() (values map[string]interface{}, origins map[string]string, includes []string, e error)
+}
+
+// layeredProvider is a provider whose sources are each merged as a layer of their own,
+// such as the files of a DirectoryProvider.
+type layeredProvider interface {
+	Provider
+	parts(prefix string) ([]*layer, error)
+}
+
+// loadProvider loads the values of the provider of a layer, flattened under its prefix,
+// with where each came from and any included files if the provider says.
+func (l *layer) loadProvider() (map[string]interface{}, map[string]string, []string, error) {
+	var nested map[string]interface{}
+	var origins map[string]string
+	var includes []string
+	var e error
+	if sp, ok := l.provider.(sourceProvider); ok {
+		nested, origins, includes, e = sp.load()
+	} else {
+		nested, e = l.provider.Load()
+	}
+	if e != nil {
+		return nil, nil, nil, e
+	}
+
+	values := make(map[string]interface{})
+	flatten(nested, l.prefix, values)
+	if l.prefix != "" && origins != nil {
+		prefixed := make(map[string]string, len(origins))
+		for k, origin := range origins {
+			prefixed[joinKey(l.prefix, k)] = origin
+		}
+		origins = prefixed
+	}
+	return values, origins, includes, nil
+}
+
+// watchableProviders returns the providers added with AddProvider that can be watched.
+func (c Config) watchableProviders() []WatchableProvider {
+	c.s.mu.RLock()
+	defer c.s.mu.RUnlock()
+
+	var providers []WatchableProvider
+	for _, l := range c.s.layers {
+		if w, ok := l.provider.(WatchableProvider); ok {
+			providers = append(providers, w)
+		}
+	}
+	return providers
+}
+
+// FileProvider provides the values of a file, as AddFileWithFormat reads them.
+type FileProvider struct {
+	Path string
+	// Format is one of the Format* constants. If it is empty, it is implied by the
+	// extension of Path (see FormatForPath).
+	Format string
+}
+
+func (p FileProvider) Load() (map[string]interface{}, error) {
+	values, _, _, e := p.load()
+	return values, e
+}
+
+func (p FileProvider) load() (map[string]interface{}, map[string]string, []string, error) {
+	format := p.Format
+	if format == "" {
+		format = FormatForPath(p.Path)
+	}
+	values := make(map[string]interface{})
+	var includes []string
+	if e := readFile(p.Path, format, "", values, nil, &includes); e != nil {
+		return nil, nil, nil, e
+	}
+	return values, nil, includes, nil
+}
+
+func (p FileProvider) Describe() (string, string) {
+	return SourceFile, p.Path
+}
+
+// EnvProvider provides the values of environment variables, mapped to keys as by
+// AddEnvironmentMapped. As it doesn't see the keys of other sources, keys are always
+// lowercase. Options.Override is ignored in favour of the setting given to AddProvider.
+type EnvProvider struct {
+	Options EnvOptions
+	// existing is set by AddEnvironmentMapped to the keys of the other sources.
+	existing map[string]string
+}
+
+func (p EnvProvider) Load() (map[string]interface{}, error) {
+	values, _, _, e := p.load()
+	return values, e
+}
+
+func (p EnvProvider) load() (map[string]interface{}, map[string]string, []string, error) {
+	values, origins := mapEnvironment(p.Options, p.existing)
+	return values, origins, nil, nil
+}
+
+func (p EnvProvider) Describe() (string, string) {
+	return SourceEnv, p.Options.Prefix
+}
+
+// FlagProvider provides the values of the flags of FlagSet (flag.CommandLine if nil) that
+// were set, as AddFlags does, except that names are not matched to the keys of other
+// sources.
+type FlagProvider struct {
+	FlagSet *flag.FlagSet
+	Options FlagOptions
+	// existing is set by AddFlags to the keys of the other sources.
+	existing map[string]string
+}
+
+func (p FlagProvider) Load() (map[string]interface{}, error) {
+	values, _, _, e := p.load()
+	return values, e
+}
+
+func (p FlagProvider) load() (map[string]interface{}, map[string]string, []string, error) {
+	values, origins := flagValues(visitFlags(p.FlagSet), p.Options, p.existing)
+	return values, origins, nil, nil
+}
+
+func (p FlagProvider) Describe() (string, string) {
+	return SourceFlag, ""
+}
+
+// PFlagProvider is like FlagProvider, but for a flag set from github.com/spf13/pflag
+// (pflag.CommandLine if nil), as AddPFlags reads it.
+type PFlagProvider struct {
+	FlagSet *pflag.FlagSet
+	Options FlagOptions
+	// existing is set by AddPFlags to the keys of the other sources.
+	existing map[string]string
+}
+
+func (p PFlagProvider) Load() (map[string]interface{}, error) {
+	values, _, _, e := p.load()
+	return values, e
+}
+
+func (p PFlagProvider) load() (map[string]interface{}, map[string]string, []string, error) {
+	values, origins := flagValues(visitPFlags(p.FlagSet), p.Options, p.existing)
+	return values, origins, nil, nil
+}
+
+func (p PFlagProvider) Describe() (string, string) {
+	return SourceFlag, ""
+}
+
+// DefaultsProvider provides fixed values, nested or with dot-delimited keys.
+type DefaultsProvider map[string]interface{}
+
+func (p DefaultsProvider) Load() (map[string]interface{}, error) {
+	return p, nil
+}
+
+func (p DefaultsProvider) Describe() (string, string) {
+	return SourceDefault, ""
+}
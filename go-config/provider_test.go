@@ -0,0 +1,261 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestProviders(t *testing.T) {
+	os.Setenv("PROVIDERTEST_DB_HOST", "envhost")
+	defer os.Unsetenv("PROVIDERTEST_DB_HOST")
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Int("db.port", 0, "")
+	fs.Parse([]string{"--db.port=6543"})
+
+	conf := NewConfig()
+	for _, p := range []Provider{
+		DefaultsProvider{"db": map[string]interface{}{"host": "localhost", "user": "app"}, "log.level": "info"},
+		FileProvider{Path: "./test.yaml"},
+		EnvProvider{Options: EnvOptions{Prefix: "PROVIDERTEST_"}},
+		FlagProvider{FlagSet: fs},
+	} {
+		if e := conf.AddProvider(p, "", true); e != nil {
+			t.Fatal(e)
+		}
+	}
+
+	if conf.GetString("db.user") != "app" || conf.GetString("log.level") != "info" {
+		t.Errorf("Expected the defaults, got %v", conf.snapshot())
+	}
+	if conf.GetString("db.host") != "envhost" || conf.GetInt("db.port") != 6543 {
+		t.Errorf("Expected db.host from the environment and db.port from the flags, got %v", conf.snapshot())
+	}
+	if conf.GetString("test1.strprop") == "" {
+		t.Errorf("Expected the values of test.yaml, got %v", conf.snapshot())
+	}
+	if e := conf.Explain("db.host"); e.Source.Kind != SourceEnv || len(e.Shadowed) != 1 || e.Shadowed[0].Kind != SourceDefault {
+		t.Errorf("Expected db.host from the environment shadowing the default, got %s", e)
+	}
+
+	if e := conf.AddProvider(FileProvider{Path: "./missing.json"}, "", false); e == nil {
+		t.Errorf("Expected an error for a missing file")
+	}
+}
+
+func TestBuiltinSourcesAreProviders(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"app.json":  `{"$include": "db.json", "name": "app"}`,
+		"db.json":   `{"db": {"maxConns": 10}}`,
+		"more.json": `{"$include": "db.json"}`,
+	})
+	os.Setenv("PROVIDERTEST_DB_MAXCONNS", "20")
+	defer os.Unsetenv("PROVIDERTEST_DB_MAXCONNS")
+
+	conf := NewConfig()
+	if e := conf.AddFile(filepath.Join(dir, "app.json"), "", false); e != nil {
+		t.Fatal(e)
+	}
+	conf.AddEnvironmentMapped(EnvOptions{Prefix: "PROVIDERTEST_", Override: true, InferTypes: true})
+	if e := conf.AddProvider(FileProvider{Path: filepath.Join(dir, "more.json")}, "", false); e != nil {
+		t.Fatal(e)
+	}
+
+	// files included by a FileProvider are watched too
+	if files := conf.files(); len(files) != 3 {
+		t.Errorf("Expected app.json, db.json and more.json to be watched, got %v", files)
+	}
+	// the environment is still matched to the keys of the file
+	if e := conf.Explain("db.maxConns"); conf.GetInt("db.maxConns") != 20 || e.Source.Kind != SourceEnv || e.Source.Name != "PROVIDERTEST_DB_MAXCONNS" {
+		t.Errorf("Expected db.maxConns from the environment, got %s", e)
+	}
+
+	// and read again by Reload
+	os.Setenv("PROVIDERTEST_DB_MAXCONNS", "30")
+	if changed, e := conf.Reload(); e != nil || !reflect.DeepEqual(changed, []string{"db.maxConns"}) {
+		t.Errorf("Expected db.maxConns to change, got %v, %v", changed, e)
+	}
+	if conf.GetInt("db.maxConns") != 30 {
+		t.Errorf("Expected the new environment to be read, got %v", conf.Get("db.maxConns"))
+	}
+}
+
+type failingProvider struct{ fail bool }
+
+func (p *failingProvider) Load() (map[string]interface{}, error) {
+	if p.fail {
+		return nil, errors.New("unavailable")
+	}
+	return map[string]interface{}{"x": "1"}, nil
+}
+
+func TestCustomProvider(t *testing.T) {
+	p := &failingProvider{}
+	conf := NewConfig()
+	if e := conf.AddProvider(p, "remote", false); e != nil {
+		t.Fatal(e)
+	}
+	if conf.GetString("remote.x") != "1" || conf.Explain("remote.x").Source.Kind != SourceProvider {
+		t.Errorf("Expected remote.x from a provider, got %s", conf.Explain("remote.x"))
+	}
+
+	p.fail = true
+	if _, e := conf.Reload(); e == nil || conf.GetString("remote.x") != "1" {
+		t.Errorf("Expected the failed reload to keep remote.x, got %v", e)
+	}
+}
+
+// configServer serves a JSON document with an ETag, answering If-None-Match with 304.
+type configServer struct {
+	mu       sync.Mutex
+	doc      string
+	fail     bool
+	requests int
+	notMod   int
+	times    []time.Time
+}
+
+func (s *configServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests++
+	s.times = append(s.times, time.Now())
+	if s.fail {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	etag := fmt.Sprintf(`"%d"`, hash(s.doc))
+	if r.Header.Get("If-None-Match") == etag {
+		s.notMod++
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("ETag", etag)
+	w.Write([]byte(s.doc))
+}
+
+func (s *configServer) set(doc string, fail bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.doc, s.fail = doc, fail
+}
+
+func hash(s string) uint32 {
+	var h uint32 = 2166136261
+	for i := 0; i < len(s); i++ {
+		h = (h ^ uint32(s[i])) * 16777619
+	}
+	return h
+}
+
+func TestHTTPProvider(t *testing.T) {
+	server := &configServer{doc: `{"feature": {"enabled": false}}`}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	p := NewHTTPProvider(ts.URL)
+	p.Interval = 10 * time.Millisecond
+	conf := NewConfig()
+	if e := conf.AddProvider(p, "", false); e != nil {
+		t.Fatal(e)
+	}
+	if conf.GetBoolOr("feature.enabled", true) || conf.Explain("feature.enabled").Source.Name != ts.URL {
+		t.Errorf("Expected feature.enabled to be false from %s, got %s", ts.URL, conf.Explain("feature.enabled"))
+	}
+
+	// an unchanged document is not sent again
+	if changed, e := conf.Reload(); e != nil || len(changed) != 0 || server.notMod != 1 {
+		t.Errorf("Expected a 304 and no changes, got %v, %v, %d", changed, e, server.notMod)
+	}
+
+	var changes int32
+	conf.OnChange(func(changed []string) {
+		atomic.AddInt32(&changes, 1)
+	})
+	w, e := conf.Watch(WatchOptions{})
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer w.Close()
+
+	server.set(`{"feature": {"enabled": true}}`, false)
+	deadline := time.Now().Add(2 * time.Second)
+	for !conf.GetBool("feature.enabled") && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if !conf.GetBool("feature.enabled") || atomic.LoadInt32(&changes) != 1 {
+		t.Errorf("Expected the watched change to be loaded once, got %v after %d changes", conf.Get("feature.enabled"), changes)
+	}
+}
+
+func TestHTTPProviderMaxSize(t *testing.T) {
+	doc := `{"padding": "` + strings.Repeat("x", 100) + `"}`
+	ts := httptest.NewServer(&configServer{doc: doc})
+	defer ts.Close()
+
+	p := NewHTTPProvider(ts.URL)
+	p.MaxSize = int64(len(doc))
+	if _, e := p.Load(); e != nil {
+		t.Errorf("Expected a document of the maximum size to be read, got %v", e)
+	}
+
+	p = NewHTTPProvider(ts.URL)
+	p.MaxSize = int64(len(doc)) - 1
+	if _, e := p.Load(); e == nil || !strings.Contains(e.Error(), "larger than") {
+		t.Errorf("Expected an error for a document that is too large, got %v", e)
+	}
+}
+
+func TestHTTPProviderBackoff(t *testing.T) {
+	server := &configServer{doc: `{"a": 1}`}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	p := NewHTTPProvider(ts.URL)
+	p.Interval, p.MaxBackoff = 10*time.Millisecond, 40*time.Millisecond
+	if _, e := p.Load(); e != nil {
+		t.Fatal(e)
+	}
+
+	server.set(`{"a": 1}`, true)
+	server.mu.Lock()
+	server.times = nil
+	server.mu.Unlock()
+
+	var errs int32
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		p.Watch(stop, func() {}, func(error) { atomic.AddInt32(&errs, 1) })
+		close(done)
+	}()
+	time.Sleep(250 * time.Millisecond)
+	close(stop)
+	<-done
+
+	server.mu.Lock()
+	times := server.times
+	server.mu.Unlock()
+	// 10, 20, 40, 40, 40 ... ms apart: without backoff there would be about 25
+	if len(times) < 3 || len(times) > 10 || int(atomic.LoadInt32(&errs)) != len(times) {
+		t.Fatalf("Expected a few failed polls, all reported, got %d polls and %d errors", len(times), errs)
+	}
+	if gap := times[2].Sub(times[1]); gap < 30*time.Millisecond {
+		t.Errorf("Expected the third poll to back off, got a gap of %v", gap)
+	}
+
+	if _, e := p.Load(); e == nil {
+		t.Errorf("Expected an error from a failing server")
+	}
+}
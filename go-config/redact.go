@@ -0,0 +1,189 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v2"
+)
+
+// FormatTable is the format for Dump that writes an aligned table of keys and values.
+const FormatTable = "table"
+
+// Redacted replaces the values of sensitive keys in Dump and DumpSources.
+const Redacted = "******"
+
+// DefaultSensitivePatterns are the patterns used by IsSensitive until SetSensitivePatterns
+// is called.
+var DefaultSensitivePatterns = []string{"password", "passwd", "token", "secret", "key", "credential"}
+
+// Logger receives debug messages about the sources added to a Config. *log.Logger
+// implements it.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// SetLogger sets the logger that debug messages are written to, such as the environment
+// variables AddEnvironment found. By default they are discarded. Messages never contain
+// values, only names, so they are safe to log.
+func (c Config) SetLogger(l Logger) {
+	c.s.mu.Lock()
+	defer c.s.mu.Unlock()
+	c.s.logger = l
+}
+
+// debugf writes a debug message to the logger, if one is set.
+func (c Config) debugf(format string, v ...interface{}) {
+	c.s.mu.RLock()
+	l := c.s.logger
+	c.s.mu.RUnlock()
+	if l != nil {
+		l.Printf(format, v...)
+	}
+}
+
+// sortedKeys returns the keys of m, sorted, for debug messages.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// SetSensitivePatterns replaces the patterns that mark keys as sensitive. A key is
+// sensitive if it contains any of the patterns, ignoring case, so "key" matches both
+// "api.key" and "db.privateKeyFile".
+func (c Config) SetSensitivePatterns(patterns ...string) {
+	lower := make([]string, len(patterns))
+	for i, p := range patterns {
+		lower[i] = strings.ToLower(p)
+	}
+
+	c.s.mu.Lock()
+	defer c.s.mu.Unlock()
+	c.s.sensitive = lower
+}
+
+// IsSensitive reports whether the value of key should be hidden when the config is shown:
+// because the key matches one of the sensitive patterns (DefaultSensitivePatterns, unless
+// changed with SetSensitivePatterns), because it was added with AddSecretsDir, or because
+// its value is an enabled secret reference (see EnableSecretRefs).
+func (c Config) IsSensitive(key string) bool {
+	full := c.full(key)
+	if c.matchesSensitive(full) {
+		return true
+	}
+
+	c.s.mu.RLock()
+	defer c.s.mu.RUnlock()
+	for _, l := range c.s.layers {
+		if _, ok := l.values[full]; ok && l.kind == SourceSecret {
+			return true
+		}
+	}
+	if r, ok := c.snapshot()[full].(secretRef); ok && c.s.secretRefs[r.kind] {
+		return true
+	}
+	return false
+}
+
+// matchesSensitive reports whether name contains one of the sensitive patterns.
+func (c Config) matchesSensitive(name string) bool {
+	c.s.mu.RLock()
+	patterns := c.s.sensitive
+	c.s.mu.RUnlock()
+	if patterns == nil {
+		patterns = DefaultSensitivePatterns
+	}
+	lower := strings.ToLower(name)
+	for _, p := range patterns {
+		if p != "" && strings.Contains(lower, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactedValues returns the flattened config, keyed as for Keys, with the values of
+// sensitive keys replaced by Redacted, as are the fields of objects within arrays whose
+// names match the sensitive patterns. Values are as they were merged: references are not
+// expanded and secrets they refer to are not read, so neither can leak.
+func (c Config) RedactedValues() map[string]interface{} {
+	values := c.view()
+	result := make(map[string]interface{}, len(values))
+	for k, v := range values {
+		result[k] = c.redact(k, plainValue(v))
+	}
+	return result
+}
+
+// redact returns v, or Redacted if key is sensitive. Objects are stored whole within
+// arrays, so their fields are redacted by name, at any depth.
+func (c Config) redact(key string, v interface{}) interface{} {
+	if c.IsSensitive(key) {
+		return Redacted
+	}
+	return c.redactFields(v)
+}
+
+// redactFields returns a copy of v with the fields of objects within it whose names are
+// sensitive replaced by Redacted. Values without objects are returned as they are.
+func (c Config) redactFields(v interface{}) interface{} {
+	switch vv := v.(type) {
+	case []interface{}:
+		result := make([]interface{}, len(vv))
+		for i, item := range vv {
+			result[i] = c.redactFields(item)
+		}
+		return result
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(vv))
+		for k, item := range vv {
+			if c.matchesSensitive(k) {
+				result[k] = Redacted
+			} else {
+				result[k] = c.redactFields(item)
+			}
+		}
+		return result
+	}
+	return v
+}
+
+// Dump writes the effective, flattened config to w in the given format: FormatJSON,
+// FormatYAML or FormatTable. It writes the values returned by RedactedValues, so it is
+// safe for logs and diagnostics.
+func (c Config) Dump(w io.Writer, format string) error {
+	values := c.RedactedValues()
+
+	switch format {
+	case FormatJSON:
+		data, e := json.MarshalIndent(values, "", "  ")
+		if e != nil {
+			return e
+		}
+		_, e = w.Write(append(data, '\n'))
+		return e
+	case FormatYAML:
+		data, e := yaml.Marshal(values)
+		if e != nil {
+			return e
+		}
+		_, e = w.Write(data)
+		return e
+	case FormatTable:
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "KEY\tVALUE")
+		for _, k := range sortedKeys(values) {
+			fmt.Fprintf(tw, "%s\t%v\n", k, values[k])
+		}
+		return tw.Flush()
+	}
+	return fmt.Errorf("config: cannot dump in format %q", format)
+}
@@ -0,0 +1,391 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Schema describes the valid values of a config, in a subset of JSON Schema. It can be
+// read from a JSON Schema document with ParseSchema, or declared in Go:
+//
+//	schema := &config.Schema{
+//		Type:     config.Types("object"),
+//		Required: []string{"db"},
+//		Properties: map[string]*config.Schema{
+//			"db": {
+//				Type:     config.Types("object"),
+//				Required: []string{"host", "port"},
+//				Properties: map[string]*config.Schema{
+//					"host": {Type: config.Types("string"), MinLength: config.Int(1)},
+//					"port": {Type: config.Types("integer"), Minimum: config.Float(1), Maximum: config.Float(65535)},
+//					"mode": {Enum: []interface{}{"primary", "replica"}},
+//				},
+//			},
+//		},
+//	}
+//
+// Since strings from the environment are parsed by the getters, a string is valid for
+// "integer", "number" and "boolean" if the getter for that type would accept it.
+type Schema struct {
+	// Type lists the allowed types: "object", "array", "string", "integer", "number",
+	// "boolean" or "null". Any type is allowed if it is empty.
+	Type       SchemaTypes        `json:"type,omitempty"`
+	Properties map[string]*Schema `json:"properties,omitempty"`
+	// Required lists the properties an object must have.
+	Required []string `json:"required,omitempty"`
+	// AdditionalProperties, if false, makes properties not listed in Properties invalid.
+	AdditionalProperties *bool `json:"additionalProperties,omitempty"`
+	// Items is the schema of each element of an array.
+	Items    *Schema `json:"items,omitempty"`
+	MinItems *int    `json:"minItems,omitempty"`
+	MaxItems *int    `json:"maxItems,omitempty"`
+	// Enum lists the allowed values.
+	Enum      []interface{} `json:"enum,omitempty"`
+	Minimum   *float64      `json:"minimum,omitempty"`
+	Maximum   *float64      `json:"maximum,omitempty"`
+	MinLength *int          `json:"minLength,omitempty"`
+	MaxLength *int          `json:"maxLength,omitempty"`
+	// Pattern is a regular expression (in Go's syntax) that strings must match.
+	Pattern string `json:"pattern,omitempty"`
+}
+
+// SchemaTypes is the list of types allowed by a Schema. In JSON it can be a single string or
+// an array of them.
+type SchemaTypes []string
+
+func (t *SchemaTypes) UnmarshalJSON(data []byte) error {
+	var one string
+	if e := json.Unmarshal(data, &one); e == nil {
+		*t = SchemaTypes{one}
+		return nil
+	}
+	var many []string
+	if e := json.Unmarshal(data, &many); e != nil {
+		return fmt.Errorf("type must be a string or an array of strings")
+	}
+	*t = many
+	return nil
+}
+
+// Types is a shorthand for declaring the Type of a Schema in Go.
+func Types(types ...string) SchemaTypes {
+	return SchemaTypes(types)
+}
+
+// Int returns a pointer to i, for the optional limits of a Schema.
+func Int(i int) *int {
+	return &i
+}
+
+// Float returns a pointer to f, for the optional limits of a Schema.
+func Float(f float64) *float64 {
+	return &f
+}
+
+// ParseSchema reads a JSON Schema document. Keywords that Schema doesn't support are
+// ignored; invalid patterns are reported.
+func ParseSchema(data []byte) (*Schema, error) {
+	var s Schema
+	if e := json.Unmarshal(data, &s); e != nil {
+		return nil, fmt.Errorf("config: invalid schema: %s", e)
+	}
+	if e := s.check(); e != nil {
+		return nil, fmt.Errorf("config: invalid schema: %s", e)
+	}
+	return &s, nil
+}
+
+// check reports invalid patterns in s and the schemas within it.
+func (s *Schema) check() error {
+	if s.Pattern != "" {
+		if _, e := regexp.Compile(s.Pattern); e != nil {
+			return e
+		}
+	}
+	for _, p := range s.Properties {
+		if e := p.check(); e != nil {
+			return e
+		}
+	}
+	if s.Items != nil {
+		return s.Items.check()
+	}
+	return nil
+}
+
+// Violation is one way in which a config does not match its schema.
+type Violation struct {
+	// Key is the key of the invalid value, such as "db.port" or "servers[1].host", or ""
+	// for the config as a whole.
+	Key     string
+	Message string
+}
+
+func (v Violation) String() string {
+	if v.Key == "" {
+		return v.Message
+	}
+	return v.Key + ": " + v.Message
+}
+
+// ValidationError lists all the violations found by Validate, sorted by key.
+type ValidationError struct {
+	Violations []Violation
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		parts[i] = v.String()
+	}
+	return "config: invalid configuration: " + strings.Join(parts, "; ")
+}
+
+// Validate checks the config (with references expanded) against s, returning a
+// *ValidationError listing every violation, or nil if it is valid. The messages of
+// violations by sensitive keys (see IsSensitive), and by keys that refer to them, give the
+// type of the value, not the value.
+func (c Config) Validate(s *Schema) error {
+	var violations []Violation
+	s.validate("", c.AsNestedMap(), c.hidesValue, &violations)
+	if len(violations) == 0 {
+		return nil
+	}
+	sort.SliceStable(violations, func(i, j int) bool {
+		return violations[i].Key < violations[j].Key
+	})
+	return &ValidationError{Violations: violations}
+}
+
+// SetSchema validates the config against s, and keeps s so that Reload rejects new values
+// that don't match it, leaving the config unchanged. It returns the result of validating
+// the current values, so it can be called at startup once the sources are added. A nil s
+// removes the schema.
+func (c Config) SetSchema(s *Schema) error {
+	c.s.mu.Lock()
+	c.s.schema = s
+	c.s.mu.Unlock()
+
+	if s == nil {
+		return nil
+	}
+	return c.Validate(s)
+}
+
+// validateValues validates values, merged from layers, that are about to replace those
+// of the store, revealing secrets and deciding which keys are sensitive as the store does.
+func (s *store) validateValues(values map[string]interface{}, layers []*layer) error {
+	if s.schema == nil {
+		return nil
+	}
+	candidate := Config{s: newStore(values)}
+	candidate.s.layers = layers
+	candidate.s.decrypter, candidate.s.secretRefs, candidate.s.sensitive = s.decrypter, s.secretRefs, s.sensitive
+	return candidate.Validate(s.schema)
+}
+
+// hidesValue reports whether the value of key, with references expanded, must not be
+// shown: because key is sensitive, or refers, directly or through other keys, to one that
+// is, such as a secret read into "postgres://app:${db.password}@db".
+func (c Config) hidesValue(key string) bool {
+	root, values := Config{s: c.s}, c.snapshot()
+	seen := make(map[string]bool)
+	var hides func(full string) bool
+	hides = func(full string) bool {
+		if seen[full] {
+			return false
+		}
+		seen[full] = true
+		if root.IsSensitive(full) {
+			return true
+		}
+		v, _ := rawLookup(values, full)
+		for _, ref := range references(v) {
+			if hides(ref) {
+				return true
+			}
+		}
+		return false
+	}
+	return hides(c.full(key))
+}
+
+func (s *Schema) validate(key string, v interface{}, sensitive func(key string) bool, violations *[]Violation) {
+	add := func(format string, args ...interface{}) {
+		*violations = append(*violations, Violation{Key: key, Message: fmt.Sprintf(format, args...)})
+	}
+	// violations end up in logs and on the debug page, so don't show secrets
+	hidden := sensitive(key)
+	// arrays of other types, such as the []string of AddDefaultStringArray, are arrays too
+	if items, ok := asArray(v); ok {
+		v = items
+	}
+
+	if len(s.Type) > 0 && !s.typeMatches(v) {
+		got := describe(v)
+		if hidden {
+			got = typeName(v)
+		}
+		add("expected %s, got %s", strings.Join(s.Type, " or "), got)
+		return
+	}
+
+	if len(s.Enum) > 0 {
+		found := false
+		for _, allowed := range s.Enum {
+			if reflect.DeepEqual(allowed, v) || fmt.Sprint(allowed) == fmt.Sprint(v) {
+				found = true
+				break
+			}
+		}
+		if !found && hidden {
+			add("is not one of %v", s.Enum)
+		} else if !found {
+			add("%v is not one of %v", v, s.Enum)
+		}
+	}
+
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := vv[name]; !ok {
+				*violations = append(*violations, Violation{Key: joinKey(key, name), Message: "is required"})
+			}
+		}
+		for name, item := range vv {
+			if p, ok := s.Properties[name]; ok {
+				p.validate(joinKey(key, name), item, sensitive, violations)
+			} else if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				*violations = append(*violations, Violation{Key: joinKey(key, name), Message: "is not allowed"})
+			}
+		}
+		return
+	case []interface{}:
+		if s.MinItems != nil && len(vv) < *s.MinItems {
+			add("has %d items, fewer than %d", len(vv), *s.MinItems)
+		}
+		if s.MaxItems != nil && len(vv) > *s.MaxItems {
+			add("has %d items, more than %d", len(vv), *s.MaxItems)
+		}
+		if s.Items != nil {
+			for i, item := range vv {
+				s.Items.validate(fmt.Sprintf("%s[%d]", key, i), item, sensitive, violations)
+			}
+		}
+		return
+	}
+
+	if s.Minimum != nil || s.Maximum != nil {
+		if f, e := toFloat64(v); e == nil {
+			if s.Minimum != nil && f < *s.Minimum {
+				if hidden {
+					add("is less than %v", *s.Minimum)
+				} else {
+					add("%v is less than %v", v, *s.Minimum)
+				}
+			}
+			if s.Maximum != nil && f > *s.Maximum {
+				if hidden {
+					add("is greater than %v", *s.Maximum)
+				} else {
+					add("%v is greater than %v", v, *s.Maximum)
+				}
+			}
+		}
+	}
+
+	if str, ok := v.(string); ok {
+		n := len([]rune(str))
+		if s.MinLength != nil && n < *s.MinLength {
+			add("is shorter than %d characters", *s.MinLength)
+		}
+		if s.MaxLength != nil && n > *s.MaxLength {
+			add("is longer than %d characters", *s.MaxLength)
+		}
+		if s.Pattern != "" {
+			re, e := regexp.Compile(s.Pattern)
+			if e != nil {
+				add("invalid pattern %q in schema: %s", s.Pattern, e)
+			} else if !re.MatchString(str) && hidden {
+				add("does not match %q", s.Pattern)
+			} else if !re.MatchString(str) {
+				add("%q does not match %q", str, s.Pattern)
+			}
+		}
+	}
+}
+
+// typeMatches reports whether v is one of the types of s.
+func (s *Schema) typeMatches(v interface{}) bool {
+	for _, t := range s.Type {
+		switch t {
+		case "object":
+			if _, ok := v.(map[string]interface{}); ok {
+				return true
+			}
+		case "array":
+			if _, ok := v.([]interface{}); ok {
+				return true
+			}
+		case "string":
+			if _, ok := v.(string); ok {
+				return true
+			}
+		case "integer":
+			if _, e := toInt64(v); e == nil {
+				return true
+			}
+			if _, e := toUint64(v); e == nil {
+				return true
+			}
+		case "number":
+			if f, e := toFloat64(v); e == nil && !math.IsNaN(f) {
+				return true
+			}
+		case "boolean":
+			if _, e := toBool(v); e == nil {
+				return true
+			}
+		case "null":
+			if v == nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// describe names the type and value of a config value for violation messages.
+func describe(v interface{}) string {
+	switch vv := v.(type) {
+	case string:
+		return fmt.Sprintf("string %q", vv)
+	case bool, float64, int, int64, uint64:
+		return fmt.Sprintf("%s %v", typeName(v), vv)
+	}
+	return typeName(v)
+}
+
+// typeName names the type of a config value as JSON Schema does.
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64, int, int64, uint64:
+		return "number"
+	}
+	return fmt.Sprintf("%T", v)
+}
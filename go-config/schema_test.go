@@ -0,0 +1,240 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testSchema = `{
+	"$schema": "http://json-schema.org/draft-07/schema#",
+	"type": "object",
+	"required": ["db", "servers"],
+	"properties": {
+		"db": {
+			"type": "object",
+			"required": ["host", "port"],
+			"additionalProperties": false,
+			"properties": {
+				"host": {"type": "string", "minLength": 1, "pattern": "^[a-z0-9.-]+$"},
+				"port": {"type": "integer", "minimum": 1, "maximum": 65535},
+				"mode": {"enum": ["primary", "replica"]},
+				"debug": {"type": ["boolean", "null"]}
+			}
+		},
+		"servers": {
+			"type": "array",
+			"minItems": 1,
+			"items": {"type": "object", "required": ["host"]}
+		}
+	}
+}`
+
+func violations(e error) []string {
+	var ve *ValidationError
+	if !errors.As(e, &ve) {
+		return nil
+	}
+	result := make([]string, len(ve.Violations))
+	for i, v := range ve.Violations {
+		result[i] = v.String()
+	}
+	return result
+}
+
+func TestValidate(t *testing.T) {
+	schema, e := ParseSchema([]byte(testSchema))
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	conf := NewConfig()
+	conf.nestedMerge(map[string]interface{}{
+		"db": map[string]interface{}{
+			"host": "db1.example.com",
+			"port": "5432", // e.g. from the environment
+			"mode": "primary",
+		},
+		"servers": []interface{}{map[string]interface{}{"host": "a"}},
+	}, "", false)
+	if e := conf.Validate(schema); e != nil {
+		t.Errorf("Expected the config to be valid, got %v", e)
+	}
+
+	conf = NewConfig()
+	conf.nestedMerge(map[string]interface{}{
+		"db": map[string]interface{}{
+			"host":  "DB_1",
+			"port":  70000.0,
+			"mode":  "standby",
+			"debug": "maybe",
+			"extra": 1.0,
+		},
+		"servers": []interface{}{map[string]interface{}{"host": "a"}, map[string]interface{}{"name": "b"}},
+	}, "", false)
+	expected := []string{
+		`db.debug: expected boolean or null, got string "maybe"`,
+		`db.extra: is not allowed`,
+		`db.host: "DB_1" does not match "^[a-z0-9.-]+$"`,
+		`db.mode: standby is not one of [primary replica]`,
+		`db.port: 70000 is greater than 65535`,
+		`servers[1].host: is required`,
+	}
+	if got := violations(conf.Validate(schema)); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected violations %q, got %q", expected, got)
+	}
+
+	if got := violations(NewConfig().Validate(schema)); !reflect.DeepEqual(got, []string{"db: is required", "servers: is required"}) {
+		t.Errorf("Expected db and servers to be required, got %q", got)
+	}
+
+	if _, e := ParseSchema([]byte(`{"pattern": "("}`)); e == nil {
+		t.Errorf("Expected an error for an invalid pattern")
+	}
+}
+
+func TestGoSchema(t *testing.T) {
+	schema := &Schema{
+		Type: Types("object"),
+		Properties: map[string]*Schema{
+			"workers": {Type: Types("integer"), Minimum: Float(1)},
+			"name":    {Type: Types("string"), MaxLength: Int(3)},
+		},
+	}
+	conf := NewConfig()
+	conf.AddDefault("workers", "0")
+	conf.AddDefault("name", "long")
+	expected := []string{"name: is longer than 3 characters", "workers: 0 is less than 1"}
+	if got := violations(conf.Validate(schema)); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected violations %q, got %q", expected, got)
+	}
+}
+
+func TestSchemaRejectsReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.json")
+	os.WriteFile(path, []byte(`{"workers": 4}`), 0644)
+
+	conf := NewConfig()
+	if e := conf.AddFile(path, "", false); e != nil {
+		t.Fatal(e)
+	}
+	schema := &Schema{Properties: map[string]*Schema{"workers": {Type: Types("integer"), Minimum: Float(1)}}}
+	if e := conf.SetSchema(schema); e != nil {
+		t.Fatalf("Expected the initial config to be valid, got %v", e)
+	}
+
+	os.WriteFile(path, []byte(`{"workers": 0}`), 0644)
+	if _, e := conf.Reload(); len(violations(e)) != 1 {
+		t.Errorf("Expected the reload to be rejected, got %v", e)
+	}
+	if conf.GetInt("workers") != 4 {
+		t.Errorf("Expected workers to still be 4, got %v", conf.Get("workers"))
+	}
+	if _, e := conf.LastReload(); e == nil {
+		t.Errorf("Expected LastReload to report the rejected reload")
+	}
+
+	os.WriteFile(path, []byte(`{"workers": 8}`), 0644)
+	if _, e := conf.Reload(); e != nil || conf.GetInt("workers") != 8 {
+		t.Errorf("Expected a valid reload to succeed, got %v, %v", e, conf.Get("workers"))
+	}
+}
+
+func TestSchemaHidesSensitiveValues(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.json")
+	os.WriteFile(path, []byte(`{"db": {"password": "hunter2hunter2"}, "mode": "primary"}`), 0644)
+
+	conf := NewConfig()
+	if e := conf.AddFile(path, "", false); e != nil {
+		t.Fatal(e)
+	}
+	schema := &Schema{Properties: map[string]*Schema{
+		"db":   {Properties: map[string]*Schema{"password": {Type: Types("string"), MinLength: Int(8), Pattern: "^[a-z0-9]+$"}}},
+		"mode": {Enum: []interface{}{"primary", "replica"}},
+	}}
+	if e := conf.SetSchema(schema); e != nil {
+		t.Fatalf("Expected the initial config to be valid, got %v", e)
+	}
+
+	os.WriteFile(path, []byte(`{"db": {"password": "Hunter2-Hunter2"}, "mode": "standby"}`), 0644)
+	_, e := conf.Reload()
+	expected := []string{`db.password: does not match "^[a-z0-9]+$"`, `mode: standby is not one of [primary replica]`}
+	if got := violations(e); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected violations %q, got %q", expected, got)
+	}
+	if info := conf.DebugInfo(); strings.Contains(info.LastReloadError, "Hunter2") {
+		t.Errorf("Expected the debug info not to show the password, got %q", info.LastReloadError)
+	}
+
+	os.WriteFile(path, []byte(`{"db": {"password": 12345678}}`), 0644)
+	_, e = conf.Reload()
+	expected = []string{"db.password: expected string, got number"}
+	if got := violations(e); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected violations %q, got %q", expected, got)
+	}
+}
+
+func TestSchemaHidesValuesFromSecrets(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"login":       "hunter2\n",
+		"secrets/dbx": "swordfish",
+	})
+
+	conf := NewConfig()
+	conf.AddDefault("db.login", "file://"+filepath.Join(dir, "login"))
+	conf.AddDefault("db.dsn", "${db.login}")
+	conf.AddDefault("db.url", "mysql://app:${dbx}@db")
+	conf.AddDefault("db.name", "app")
+	conf.AddDefault("db.alias", "${db.name}")
+	if e := conf.AddSecretsDir(filepath.Join(dir, "secrets"), ""); e != nil {
+		t.Fatal(e)
+	}
+	conf.EnableSecretRefs(SourceDefault)
+
+	schema := &Schema{Properties: map[string]*Schema{"db": {Properties: map[string]*Schema{
+		"dsn":   {Type: Types("string"), Pattern: "^postgres://"},
+		"url":   {Type: Types("string"), Pattern: "^postgres://"},
+		"alias": {Type: Types("string"), Pattern: "^postgres://"},
+	}}}}
+	expected := []string{
+		`db.alias: "app" does not match "^postgres://"`,
+		`db.dsn: does not match "^postgres://"`,
+		`db.url: does not match "^postgres://"`,
+	}
+	if got := violations(conf.Validate(schema)); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected violations %q, got %q", expected, got)
+	}
+}
+
+func TestSchemaStringArrays(t *testing.T) {
+	conf := NewConfig()
+	conf.AddDefaultStringArray("origins", []string{"https://a.example", "http://b.example"})
+
+	schema := &Schema{Properties: map[string]*Schema{
+		"origins": {Type: Types("array"), MinItems: Int(1), Items: &Schema{Type: Types("string"), Pattern: "^https://"}},
+	}}
+	expected := []string{`origins[1]: "http://b.example" does not match "^https://"`}
+	if got := violations(conf.SetSchema(schema)); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected violations %q, got %q", expected, got)
+	}
+
+	schema.Properties["origins"].Items = nil
+	schema.Properties["origins"].MaxItems = Int(1)
+	expected = []string{"origins: has 2 items, more than 1"}
+	if got := violations(conf.Validate(schema)); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected violations %q, got %q", expected, got)
+	}
+
+	// and a reload of a valid config is accepted
+	schema.Properties["origins"].MaxItems = nil
+	if e := conf.SetSchema(schema); e != nil {
+		t.Fatal(e)
+	}
+	if _, e := conf.Reload(); e != nil {
+		t.Errorf("Expected the reload to be accepted, got %v", e)
+	}
+}
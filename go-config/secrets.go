@@ -0,0 +1,256 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SourceSecret is the kind of source for values added with AddSecretsDir.
+const SourceSecret = "secret"
+
+// Prefixes of values that are secret references rather than literal values.
+const (
+	filePrefix      = "file://"
+	encryptedPrefix = "enc:"
+)
+
+// secretRef is a string value of the form "file://..." or "enc:...", marked when it was
+// merged with the kind of source it came from. It is revealed only if EnableSecretRefs was
+// called for that kind; otherwise it reads as the string it is.
+type secretRef struct {
+	kind string
+	ref  string
+}
+
+func (r secretRef) String() string {
+	return r.ref
+}
+
+// markSecretRef returns v as a secretRef if it is a string that looks like one.
+func markSecretRef(v interface{}, kind string) interface{} {
+	if s, ok := v.(string); ok && (strings.HasPrefix(s, filePrefix) || strings.HasPrefix(s, encryptedPrefix)) {
+		return secretRef{kind: kind, ref: s}
+	}
+	return v
+}
+
+// plainValue returns v with a secretRef turned back into its string, for showing stored
+// values without revealing anything.
+func plainValue(v interface{}) interface{} {
+	if r, ok := v.(secretRef); ok {
+		return r.ref
+	}
+	return v
+}
+
+// EnableSecretRefs makes the "file://" and "enc:" values from sources of the given kinds,
+// such as SourceFile, secret references: a "file://" URL reads as the contents of the file
+// it names (e.g. "file:///run/secrets/db_password"), and "enc:" text as its decrypted
+// value. Until it is called, such values are ordinary strings, so that a "file://" URL
+// setting is read as a URL, and the environment or a remote Provider can't make the
+// process read local files. Only whole values are references, not parts of a string or
+// the elements of an array, but a "${db.password}" reference to one gives the secret, even
+// within a longer string. What they refer to is read once and kept until Reload.
+func (c Config) EnableSecretRefs(kinds ...string) {
+	c.s.mu.Lock()
+	defer c.s.mu.Unlock()
+	enabled := make(map[string]bool, len(c.s.secretRefs)+len(kinds))
+	for k := range c.s.secretRefs {
+		enabled[k] = true
+	}
+	for _, k := range kinds {
+		enabled[k] = true
+	}
+	c.s.secretRefs = enabled
+}
+
+// clearSecrets forgets the secrets read so far. The caller holds the lock.
+func (s *store) clearSecrets() {
+	s.secretCache = nil
+	s.secretGen++
+}
+
+// Decrypter decrypts values of the form "enc:<ciphertext>". Decrypt is given the text after
+// "enc:". NewAESGCMDecrypter provides one for AES-GCM; other schemes, such as age, can be
+// plugged in by implementing this interface.
+type Decrypter interface {
+	Decrypt(ciphertext string) (string, error)
+}
+
+// SetDecrypter sets the Decrypter used for encrypted values (see EnableSecretRefs). Until
+// it is set, reading an encrypted value gives an error.
+func (c Config) SetDecrypter(d Decrypter) {
+	c.s.mu.Lock()
+	defer c.s.mu.Unlock()
+	c.s.decrypter = d
+	c.s.clearSecrets()
+}
+
+// revealSecret returns the secret a secretRef refers to, if references are enabled for
+// its kind of source: the contents of the file named by a "file://" URL, or the decrypted
+// text of an "enc:" value. Other secretRefs are returned as strings, and other values as
+// they are. Secrets are cached until the next Reload.
+func (c Config) revealSecret(v interface{}) (interface{}, error) {
+	r, ok := v.(secretRef)
+	if !ok {
+		return v, nil
+	}
+
+	c.s.mu.RLock()
+	enabled, d, gen := c.s.secretRefs[r.kind], c.s.decrypter, c.s.secretGen
+	cached, ok := c.s.secretCache[r.ref]
+	c.s.mu.RUnlock()
+	if !enabled {
+		return r.ref, nil
+	}
+	if ok {
+		return cached, nil
+	}
+
+	var secret string
+	var e error
+	if strings.HasPrefix(r.ref, filePrefix) {
+		secret, e = readSecretFile(r.ref[len(filePrefix):])
+	} else if d == nil {
+		e = errors.New("encrypted value, but no decrypter is set")
+	} else {
+		secret, e = d.Decrypt(r.ref[len(encryptedPrefix):])
+	}
+	if e != nil {
+		return nil, e
+	}
+
+	c.s.mu.Lock()
+	// unless a reload has happened meanwhile, which may have made this out of date
+	if c.s.secretGen == gen {
+		if c.s.secretCache == nil {
+			c.s.secretCache = make(map[string]string)
+		}
+		c.s.secretCache[r.ref] = secret
+	}
+	c.s.mu.Unlock()
+	return secret, nil
+}
+
+// readSecretFile reads a secret from a file, without the trailing newline that editors and
+// "echo" usually leave.
+func readSecretFile(path string) (string, error) {
+	data, e := ioutil.ReadFile(path)
+	if e != nil {
+		return "", e
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// AddSecretsDir adds a value for each file in dir, such as the /run/secrets directory of a
+// Docker container or a mounted Kubernetes secret. The name of each file is its key (under
+// prefix, which can be ""), and its contents, without a trailing newline, are the value,
+// so /run/secrets/db_password is read with GetString("db_password"). Hidden files and
+// directories are skipped. Secrets override existing settings, and are read again by
+// Reload, so rotated secrets are picked up.
+func (c Config) AddSecretsDir(dir string, prefix string) error {
+	l, e := (&layer{kind: SourceSecret, name: dir, prefix: c.full(prefix), override: true}).read()
+	if e != nil {
+		return e
+	}
+
+	c.add(&l)
+	c.debugf("config: AddSecretsDir(%q): found %s", dir, sortedKeys(l.values))
+
+	return nil
+}
+
+// readSecretsDir reads the files in dir as values under prefix.
+func readSecretsDir(dir string, prefix string) (map[string]interface{}, error) {
+	entries, e := ioutil.ReadDir(dir)
+	if e != nil {
+		return nil, e
+	}
+
+	values := make(map[string]interface{})
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		// stat rather than use entry, so that symbolic links (as used by Kubernetes) are followed
+		info, e := os.Stat(path)
+		if e != nil {
+			return nil, e
+		}
+		if info.IsDir() {
+			continue
+		}
+		s, e := readSecretFile(path)
+		if e != nil {
+			return nil, e
+		}
+		values[joinKey(prefix, name)] = s
+	}
+	return values, nil
+}
+
+type aesGCMDecrypter struct {
+	aead cipher.AEAD
+}
+
+// NewAESGCMDecrypter returns a Decrypter for values encrypted with AES-GCM under key, which
+// must be 16, 24 or 32 bytes long. The ciphertext is base64 encoded, with the nonce before
+// the sealed text, as produced by EncryptAESGCM.
+func NewAESGCMDecrypter(key []byte) (Decrypter, error) {
+	aead, e := newAESGCM(key)
+	if e != nil {
+		return nil, e
+	}
+	return &aesGCMDecrypter{aead: aead}, nil
+}
+
+func (d *aesGCMDecrypter) Decrypt(ciphertext string) (string, error) {
+	data, e := base64.StdEncoding.DecodeString(strings.TrimSpace(ciphertext))
+	if e != nil {
+		return "", fmt.Errorf("invalid encrypted value: %s", e)
+	}
+	n := d.aead.NonceSize()
+	if len(data) < n {
+		return "", errors.New("invalid encrypted value: too short")
+	}
+	plain, e := d.aead.Open(nil, data[:n], data[n:], nil)
+	if e != nil {
+		return "", fmt.Errorf("cannot decrypt value: %s", e)
+	}
+	return string(plain), nil
+}
+
+// EncryptAESGCM encrypts plaintext with AES-GCM under key, returning a value of the form
+// "enc:<base64>" to put in a config file, which a Config with NewAESGCMDecrypter(key) set
+// reads back as plaintext.
+func EncryptAESGCM(key []byte, plaintext string) (string, error) {
+	aead, e := newAESGCM(key)
+	if e != nil {
+		return "", e
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, e := io.ReadFull(rand.Reader, nonce); e != nil {
+		return "", e
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func newAESGCM(key []byte) (cipher.AEAD, error) {
+	block, e := aes.NewCipher(key)
+	if e != nil {
+		return nil, e
+	}
+	return cipher.NewGCM(block)
+}
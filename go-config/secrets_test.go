@@ -0,0 +1,204 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFileSecrets(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "db_password")
+	os.WriteFile(path, []byte("s3cret\n"), 0600)
+
+	conf := NewConfig()
+	conf.EnableSecretRefs(SourceDefault)
+	conf.AddDefault("db.password", "file://"+path)
+	conf.AddDefault("db.dir", dir)
+	conf.AddDefault("db.other", "file://${db.dir}/db_password")
+	conf.AddDefault("db.missing", "file://"+filepath.Join(dir, "missing"))
+
+	if conf.GetString("db.password") != "s3cret" || conf.GetString("db.other") != "s3cret" {
+		t.Errorf("Expected db.password and db.other to be read from the file, got '%v' and '%v'", conf.Get("db.password"), conf.Get("db.other"))
+	}
+	if _, e := conf.GetStringE("db.missing"); e == nil || !strings.Contains(e.Error(), "db.missing") {
+		t.Errorf("Expected an error naming db.missing, got %v", e)
+	}
+}
+
+func TestAddSecretsDir(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "db_password"), []byte("s3cret\n"), 0600)
+	os.WriteFile(filepath.Join(dir, "api_token"), []byte("t0ken"), 0600)
+	os.WriteFile(filepath.Join(dir, ".hidden"), []byte("x"), 0600)
+	os.Mkdir(filepath.Join(dir, "..data"), 0700)
+
+	conf := NewConfig()
+	conf.AddDefault("secrets.db_password", "from default")
+	if e := conf.AddSecretsDir(dir, "secrets"); e != nil {
+		t.Fatal(e)
+	}
+
+	if conf.GetString("secrets.db_password") != "s3cret" || conf.GetString("secrets.api_token") != "t0ken" {
+		t.Errorf("Expected the secrets to be read, got %v", conf.Keys())
+	}
+	if len(conf.Keys()) != 2 {
+		t.Errorf("Expected hidden files and directories to be skipped, got %v", conf.Keys())
+	}
+	if e := conf.Explain("secrets.api_token"); e.Source == nil || e.Source.Kind != SourceSecret || e.Source.Name != dir {
+		t.Errorf("Expected api_token to come from the secrets directory, got %s", e)
+	}
+
+	// rotated secrets are picked up by Reload
+	os.WriteFile(filepath.Join(dir, "api_token"), []byte("n3w"), 0600)
+	changed, e := conf.Reload()
+	if e != nil || len(changed) != 1 || conf.GetString("secrets.api_token") != "n3w" {
+		t.Errorf("Expected Reload to read the new token, got %v, %v, '%v'", changed, e, conf.Get("secrets.api_token"))
+	}
+
+	if e := conf.AddSecretsDir(filepath.Join(dir, "missing"), ""); e == nil {
+		t.Errorf("Expected an error for a missing directory")
+	}
+}
+
+func TestEncryptedValues(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, 32)
+	enc, e := EncryptAESGCM(key, "hunter2")
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	conf := NewConfig()
+	conf.EnableSecretRefs(SourceDefault)
+	conf.AddDefault("db.password", enc)
+	if _, e := conf.GetStringE("db.password"); e == nil || !strings.Contains(e.Error(), "no decrypter") {
+		t.Errorf("Expected an error without a decrypter, got %v", e)
+	}
+
+	d, e := NewAESGCMDecrypter(key)
+	if e != nil {
+		t.Fatal(e)
+	}
+	conf.SetDecrypter(d)
+	if conf.GetString("db.password") != "hunter2" {
+		t.Errorf("Expected db.password to be decrypted, got '%v'", conf.Get("db.password"))
+	}
+
+	other, _ := NewAESGCMDecrypter(bytes.Repeat([]byte{8}, 32))
+	conf.SetDecrypter(other)
+	if _, e := conf.GetStringE("db.password"); e == nil {
+		t.Errorf("Expected an error decrypting with the wrong key")
+	}
+	conf.AddDefault("bad", "enc:not base64!")
+	if _, e := conf.GetStringE("bad"); e == nil {
+		t.Errorf("Expected an error for an invalid encrypted value")
+	}
+
+	if _, e := NewAESGCMDecrypter([]byte("short")); e == nil {
+		t.Errorf("Expected an error for an invalid key")
+	}
+}
+
+func TestSecretRefsOptIn(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "db_password")
+	os.WriteFile(path, []byte("s3cret\n"), 0600)
+	t.Setenv("SECRETREF_DB_PASSWORD", "file://"+path)
+
+	conf := NewConfig()
+	conf.AddDefault("storage.url", "file:///tmp")
+	conf.AddDefault("db.password", "file://"+path)
+	conf.AddEnvironmentMapped(EnvOptions{Prefix: "SECRETREF_", Override: true})
+
+	// until enabled, references are ordinary strings
+	if conf.GetString("storage.url") != "file:///tmp" || !conf.HasKey("storage.url") || conf.GetURL("storage.url") == nil {
+		t.Errorf("Expected storage.url to be a URL, got %#v", conf.Get("storage.url"))
+	}
+	if conf.GetString("db.password") != "file://"+path {
+		t.Errorf("Expected db.password not to be read from the file, got %v", conf.Get("db.password"))
+	}
+
+	// enabled for defaults, but not for the environment, which set db.password
+	conf.EnableSecretRefs(SourceDefault)
+	if conf.GetString("db.password") != "file://"+path {
+		t.Errorf("Expected db.password from the environment not to be read, got %v", conf.Get("db.password"))
+	}
+	if _, e := conf.GetStringE("storage.url"); e == nil || !conf.IsSensitive("storage.url") {
+		t.Errorf("Expected storage.url, a default, to be read as a secret")
+	}
+	conf.EnableSecretRefs(SourceEnv)
+	if conf.GetString("db.password") != "s3cret" {
+		t.Errorf("Expected db.password to be read from the file, got %v", conf.Get("db.password"))
+	}
+
+	// the secret is kept until Reload
+	os.WriteFile(path, []byte("n3w\n"), 0600)
+	if conf.GetString("db.password") != "s3cret" {
+		t.Errorf("Expected the secret to be cached, got %v", conf.Get("db.password"))
+	}
+	if _, e := conf.Reload(); e != nil {
+		t.Fatal(e)
+	}
+	if conf.GetString("db.password") != "n3w" {
+		t.Errorf("Expected Reload to read the secret again, got %v", conf.Get("db.password"))
+	}
+
+	// and never shown, nor are the references
+	var buf bytes.Buffer
+	conf.Dump(&buf, FormatJSON)
+	if strings.Contains(buf.String(), "n3w") || strings.Contains(buf.String(), "file://") {
+		t.Errorf("Expected secret references to be redacted, got %s", buf.String())
+	}
+	buf.Reset()
+	conf.Encode(&buf, FormatJSON)
+	if strings.Contains(buf.String(), "n3w") || !strings.Contains(buf.String(), `"url": "file:///tmp"`) {
+		t.Errorf("Expected references to be encoded as written, got %s", buf.String())
+	}
+}
+
+func TestSecretRefsInReferences(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "db_password")
+	os.WriteFile(path, []byte("s3cret\n"), 0600)
+
+	conf := NewConfig()
+	conf.AddDefault("db.password", "file://"+path)
+	conf.AddDefault("db.dsn", "postgres://app:${db.password}@db/app")
+	conf.AddDefault("db.pw", "${db.password}")
+	conf.AddProvider(DefaultsProvider{"db.passwords": []interface{}{"${db.password}"}}, "", false)
+	conf.AddDefault("cache.dir", "file:///var/cache")
+	conf.AddDefault("cache.url", "${cache.dir}/app")
+
+	// until enabled, the reference is formatted as the string it is
+	if dsn := conf.GetString("db.dsn"); dsn != "postgres://app:file://"+path+"@db/app" {
+		t.Errorf("Expected the file URL in the DSN, got %v", dsn)
+	}
+
+	conf.EnableSecretRefs(SourceDefault)
+	for key, expected := range map[string]string{
+		// whether the reference is the whole value or part of it
+		"db.pw":        "s3cret",
+		"db.dsn":       "postgres://app:s3cret@db/app",
+		"db.passwords": "[s3cret]",
+	} {
+		if v := fmt.Sprintf("%v", conf.Get(key)); v != expected {
+			t.Errorf("Expected %s to be %s, got %s", key, expected, v)
+		}
+	}
+
+	// a secret that can't be read is an error
+	os.Remove(path)
+	conf.Reload()
+	var ie *InterpolationError
+	if _, e := conf.GetStringE("db.dsn"); !errors.As(e, &ie) || !reflect.DeepEqual(ie.Keys, []string{"db.dsn", "db.password"}) {
+		t.Errorf("Expected an interpolation error reading db.password, got %v", e)
+	}
+	if _, e := conf.GetStringE("cache.url"); e == nil {
+		t.Errorf("Expected cache.dir, an unreadable secret, to be an error in cache.url")
+	}
+}
@@ -0,0 +1,100 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// TestConcurrentAccess is meant to be run with -race: readers use every kind of read while
+// writers add values, reload and change settings.
+func TestConcurrentAccess(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.json")
+	ioutil.WriteFile(path, []byte(`{"db": {"host": "db1", "password": "x"}}`), 0644)
+
+	conf := NewConfig()
+	if e := conf.AddFile(path, "", false); e != nil {
+		t.Fatal(e)
+	}
+	conf.OnChange(func(changed []string) {})
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				conf.GetString("db.host")
+				conf.GetIntOr("counter", 0)
+				conf.Sub("db").Keys()
+				conf.Explain("db.host")
+				conf.AsNestedMap()
+				conf.Dump(&bytes.Buffer{}, FormatJSON)
+				conf.DebugInfo()
+				conf.Snapshot().GetString("db.host")
+			}
+		}()
+	}
+
+	for i := 0; i < 100; i++ {
+		conf.AddDefaultIntOverride("counter", i)
+		conf.Sub("db").AddDefaultOverride("port", fmt.Sprint(i))
+		if i%10 == 0 {
+			ioutil.WriteFile(path, []byte(fmt.Sprintf(`{"db": {"host": "db%d"}}`, i)), 0644)
+			conf.Reload()
+			conf.SetSensitivePatterns("password", "host")
+		}
+	}
+	close(stop)
+	wg.Wait()
+
+	if conf.GetInt("counter") != 99 || conf.GetString("db.host") != "db90" {
+		t.Errorf("Expected the last writes to win, got %v and %v", conf.Get("counter"), conf.Get("db.host"))
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.json")
+	ioutil.WriteFile(path, []byte(`{"a": 1, "b": 1}`), 0644)
+
+	conf := NewConfig()
+	conf.AddFile(path, "", false)
+	snapshot := conf.Snapshot()
+
+	ioutil.WriteFile(path, []byte(`{"a": 2, "b": 2}`), 0644)
+	conf.Reload()
+	conf.AddDefaultOverride("c", "3")
+
+	if snapshot.GetInt("a") != 1 || snapshot.GetInt("b") != 1 || snapshot.HasKey("c") {
+		t.Errorf("Expected the snapshot not to change, got %v", snapshot.snapshot())
+	}
+	if e := snapshot.Explain("a"); e.Source == nil || e.Source.Value != 1.0 {
+		t.Errorf("Expected the snapshot to explain its own value, got %s", e)
+	}
+	if conf.GetInt("a") != 2 || conf.GetInt("c") != 3 {
+		t.Errorf("Expected the config to change, got %v", conf.snapshot())
+	}
+
+	// a snapshot of a view is still a view
+	if conf.Sub("x").Snapshot().prefix != "x" {
+		t.Errorf("Expected a snapshot of a view to keep its prefix")
+	}
+}
+
+func BenchmarkGetStringParallel(b *testing.B) {
+	conf, _ := ReadFromFile("./test.json")
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			conf.GetString("test1.strprop")
+		}
+	})
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"strings"
+)
+
+// Sub returns a view of the keys under prefix, so that Get("host") on c.Sub("db") reads
+// "db.host". Views share the state of c: they see values added or reloaded later, and
+// values added through a view are placed under its prefix. This lets a library be handed
+// its own section of the configuration without knowing where it lives.
+func (c Config) Sub(prefix string) Config {
+	return Config{s: c.s, prefix: c.full(prefix)}
+}
+
+// Keys returns all the keys in the config, sorted. For a view created by Sub, the keys
+// are relative to its prefix.
+func (c Config) Keys() []string {
+	return sortedKeys(c.view())
+}
+
+// KeysWithPrefix returns the sorted keys that are prefix itself or lie under it, so
+// KeysWithPrefix("db") includes "db.host" but not "dbname".
+func (c Config) KeysWithPrefix(prefix string) []string {
+	var keys []string
+	for _, k := range c.Keys() {
+		if k == prefix || strings.HasPrefix(k, prefix+".") || prefix == "" {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// AsNestedMap rebuilds the tree of objects that the dot-delimited keys were flattened
+// from, so "db.host" becomes {"db": {"host": ...}}. If a key has a value and also has keys
+// under it (e.g. "db" and "db.host" were both set), the keys under it win. References are
+// expanded; values that can't be expanded are left as they are.
+func (c Config) AsNestedMap() map[string]interface{} {
+	return c.nestedMap(true)
+}
+
+// nestedMap implements AsNestedMap, expanding values only if expand is set.
+func (c Config) nestedMap(expand bool) map[string]interface{} {
+	snapshot := c.snapshot()
+	values := c.viewOf(snapshot)
+	result := make(map[string]interface{})
+	// in sorted order a parent key is seen before the keys under it
+	for _, k := range sortedKeys(values) {
+		v := plainValue(values[k])
+		if expand {
+			if x, e := c.valueIn(snapshot, k); e == nil {
+				v = x
+			}
+		}
+		setNested(result, strings.Split(k, "."), v)
+	}
+	return result
+}
+
+// setNested sets the value at path within m, creating the objects along it.
+func setNested(m map[string]interface{}, path []string, v interface{}) {
+	for _, p := range path[:len(path)-1] {
+		child, ok := m[p].(map[string]interface{})
+		if !ok {
+			child = make(map[string]interface{})
+			m[p] = child
+		}
+		m = child
+	}
+	m[path[len(path)-1]] = v
+}
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSub(t *testing.T) {
+	conf, _ := ReadFromFile("./test.json")
+
+	child1 := conf.Sub("test1").Sub("child1")
+	if child1.GetString("child1_2.x") != "c1_2" || child1.GetInt("child1prop") != 101 {
+		t.Errorf("Expected child1 view to read child1_2.x and child1prop, got '%v' and '%v'", child1.Get("child1_2.x"), child1.Get("child1prop"))
+	}
+	if !reflect.DeepEqual(child1.Keys(), []string{"child1_2.x", "child1prop"}) {
+		t.Errorf("Expected child1 view to have keys [child1_2.x child1prop], got %v", child1.Keys())
+	}
+	if child1.HasKey("test2.test2prop") {
+		t.Errorf("Expected child1 view not to see test2.test2prop")
+	}
+
+	// values added through a view land under its prefix, and views see later changes
+	child1.AddDefault("added", "yes")
+	if conf.GetString("test1.child1.added") != "yes" {
+		t.Errorf("Expected a default added through a view to be test1.child1.added")
+	}
+	conf.AddDefaultOverride("test1.child1.child1prop", "102")
+	if child1.GetInt("child1prop") != 102 {
+		t.Errorf("Expected child1 view to see the new child1prop, got '%v'", child1.Get("child1prop"))
+	}
+	if e := child1.Explain("added"); e.Source == nil || e.Source.Kind != SourceDefault {
+		t.Errorf("Expected added to be explained as a default, got %s", e)
+	}
+}
+
+func TestKeysWithPrefix(t *testing.T) {
+	conf, _ := ReadFromFile("./test.json")
+	conf.AddDefault("test10", "x")
+
+	expected := []string{"test1.child2.menuTTL", "test1.child2.siteConfigTTL", "test1.child2.siteTreeTTL"}
+	if !reflect.DeepEqual(conf.KeysWithPrefix("test1.child2"), expected) {
+		t.Errorf("Expected keys %v, got %v", expected, conf.KeysWithPrefix("test1.child2"))
+	}
+	if len(conf.KeysWithPrefix("test1")) != 9 {
+		t.Errorf("Expected 9 keys under test1 (and not test10), got %v", conf.KeysWithPrefix("test1"))
+	}
+}
+
+func TestAsNestedMap(t *testing.T) {
+	conf, _ := ReadFromFile("./test.json")
+	nested := conf.Sub("test1").AsNestedMap()
+
+	child1, ok := nested["child1"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected child1 to be an object, got %#v", nested["child1"])
+	}
+	if !reflect.DeepEqual(child1["child1_2"], map[string]interface{}{"x": "c1_2"}) || child1["child1prop"] != 101.0 {
+		t.Errorf("Expected child1 to be rebuilt, got %#v", child1)
+	}
+	if a, ok := nested["arrayprop"].([]interface{}); !ok || len(a) != 3 {
+		t.Errorf("Expected arrayprop to be kept as an array, got %#v", nested["arrayprop"])
+	}
+}
@@ -0,0 +1,380 @@
+package config
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// OnChange registers fn to be called after each reload (or change of array merge strategy)
+// that changed the config. It is passed the sorted keys that were added, removed or changed.
+func (c Config) OnChange(fn func(changed []string)) {
+	c.s.mu.Lock()
+	defer c.s.mu.Unlock()
+	c.s.listeners = append(c.s.listeners, fn)
+}
+
+// notify calls listeners with the changed keys, if there are any. The lock must not be
+// held, so that listeners can read the config.
+func notify(listeners []func(changed []string), changed []string) {
+	if len(changed) == 0 {
+		return
+	}
+	for _, fn := range listeners {
+		fn(changed)
+	}
+}
+
+// LastReload returns the time of the last call to Reload, and the error it returned. The
+// time is zero if the config has never been reloaded.
+func (c Config) LastReload() (time.Time, error) {
+	c.s.mu.RLock()
+	defer c.s.mu.RUnlock()
+	return c.s.lastReload, c.s.lastReloadErr
+}
+
+// Reload re-reads every file added with AddFile, every directory added with AddDirectory or
+// AddSecretsDir, the variables and flags added with AddEnvironmentMapped, AddFlags and
+// AddPFlags, and every provider added with AddProvider, and rebuilds the config by merging
+// all sources again in the order they were originally added, with the same override
+// settings. The new values replace the
+// old ones in one step, so readers see either the old or the new config, never a mix. If
+// any file can't be read, or the new values don't match the schema set with SetSchema, the
+// config is left unchanged and the error returned. Otherwise the listeners registered with
+// OnChange are called with the changed keys, and those keys are returned.
+func (c Config) Reload() ([]string, error) {
+	c.s.mu.RLock()
+	layers := append([]*layer(nil), c.s.layers...)
+	c.s.mu.RUnlock()
+
+	// read the files before taking the write lock
+	fresh := make(map[*layer]*layer)
+	for _, l := range layers {
+		if l.kind != SourceSecret && l.provider == nil {
+			continue
+		}
+		read, e := l.read()
+		if e != nil {
+			c.s.mu.Lock()
+			c.s.lastReload, c.s.lastReloadErr = time.Now(), e
+			c.s.mu.Unlock()
+			return nil, e
+		}
+		fresh[l] = &read
+	}
+
+	c.s.mu.Lock()
+	values := make(map[string]interface{})
+	applied := make([]*layer, len(c.s.layers))
+	for i, l := range c.s.layers {
+		applied[i] = l
+		if read, ok := fresh[l]; ok {
+			applied[i] = read
+		}
+		applied[i].apply(values, c.s.arrayMerges)
+	}
+	if e := c.s.validateValues(values, applied); e != nil {
+		c.s.lastReload, c.s.lastReloadErr = time.Now(), e
+		c.s.mu.Unlock()
+		return nil, e
+	}
+	for l, read := range fresh {
+		l.values, l.origins, l.includes, l.parts = read.values, read.origins, read.includes, read.parts
+	}
+	changed := changedKeys(c.snapshot(), values)
+	c.s.values.Store(&values)
+	c.s.clearSecrets()
+	c.s.lastReload, c.s.lastReloadErr = time.Now(), nil
+	listeners := append([]func(changed []string){}, c.s.listeners...)
+	c.s.mu.Unlock()
+
+	notify(listeners, changed)
+	return changed, nil
+}
+
+// read reads the provider (or secrets directory) a layer comes from, returning a copy of
+// the layer with its flattened values, where they came from and any files it included.
+func (l *layer) read() (layer, error) {
+	// Reload calls this without the lock, so only the fields that don't change are copied
+	read := layer{kind: l.kind, name: l.name, prefix: l.prefix, override: l.override, provider: l.provider}
+	var e error
+	if lp, ok := l.provider.(layeredProvider); ok {
+		read.parts, e = lp.parts(l.prefix)
+	} else if l.kind == SourceSecret {
+		read.values, e = readSecretsDir(l.name, l.prefix)
+	} else {
+		read.values, read.origins, read.includes, e = l.loadProvider()
+	}
+	return read, e
+}
+
+// changedKeys returns the sorted keys whose values differ between old and new.
+func changedKeys(old, new map[string]interface{}) []string {
+	var changed []string
+	for k, v := range new {
+		if ov, ok := old[k]; !ok || !reflect.DeepEqual(ov, v) {
+			changed = append(changed, k)
+		}
+	}
+	for k := range old {
+		if _, ok := new[k]; !ok {
+			changed = append(changed, k)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// files returns the absolute paths of the files added with AddFile, and the files they
+// include.
+func (c Config) files() []string {
+	c.s.mu.RLock()
+	defer c.s.mu.RUnlock()
+
+	var paths []string
+	seen := make(map[string]bool)
+	for _, l := range flatLayers(c.s.layers) {
+		if l.kind != SourceFile {
+			continue
+		}
+		for _, name := range append([]string{l.name}, l.includes...) {
+			path := absPath(name)
+			if !seen[path] {
+				seen[path] = true
+				paths = append(paths, path)
+			}
+		}
+	}
+	return paths
+}
+
+// dirs returns the absolute paths of the directories added with AddDirectory.
+func (c Config) dirs() []string {
+	c.s.mu.RLock()
+	defer c.s.mu.RUnlock()
+
+	var paths []string
+	for _, l := range c.s.layers {
+		if d, ok := l.provider.(DirectoryProvider); ok {
+			paths = append(paths, absPath(d.Dir))
+		}
+	}
+	return paths
+}
+
+// absPath returns the absolute form of path, or path if it can't be made absolute.
+func absPath(path string) string {
+	if abs, e := filepath.Abs(path); e == nil {
+		return abs
+	}
+	return path
+}
+
+// WatchOptions controls how Watch detects changes.
+type WatchOptions struct {
+	// Poll forces polling of the files' modification time and size, rather than using
+	// file system notifications. Polling is also used when notifications are unavailable.
+	Poll bool
+	// Interval is the polling interval. It defaults to 2 seconds.
+	Interval time.Duration
+	// Delay is how long to wait for further changes after a notification before
+	// reloading, as editors often write a file in several steps. It defaults to 100ms.
+	Delay time.Duration
+	// OnError is called with errors from reloading or watching. It may be nil.
+	OnError func(error)
+}
+
+// Watcher reloads a Config when its files or providers change. Create one with Config.Watch.
+type Watcher struct {
+	c     Config
+	opts  WatchOptions
+	files map[string]bool
+	dirs  map[string]bool
+	fsw   *fsnotify.Watcher
+	done  chan struct{}
+	wg    sync.WaitGroup
+}
+
+// Watch starts watching the files that were added with AddFile, the directories added
+// with AddDirectory, and the providers added with AddProvider that implement
+// WatchableProvider, calling Reload when any of them changes. Sources added after Watch is called are not watched. Call Close on the
+// returned Watcher to stop watching.
+func (c Config) Watch(opts WatchOptions) (*Watcher, error) {
+	if opts.Interval <= 0 {
+		opts.Interval = 2 * time.Second
+	}
+	if opts.Delay <= 0 {
+		opts.Delay = 100 * time.Millisecond
+	}
+
+	w := &Watcher{
+		c:     c,
+		opts:  opts,
+		files: make(map[string]bool),
+		dirs:  make(map[string]bool),
+		done:  make(chan struct{}),
+	}
+	for _, path := range c.files() {
+		w.files[path] = true
+	}
+	for _, path := range c.dirs() {
+		w.dirs[path] = true
+	}
+	providers := c.watchableProviders()
+	if len(w.files) == 0 && len(w.dirs) == 0 && len(providers) == 0 {
+		return nil, errors.New("config: no files to watch")
+	}
+
+	if len(w.files) > 0 || len(w.dirs) > 0 {
+		if !opts.Poll {
+			w.fsw = newNotifyWatcher(w.files, w.dirs)
+		}
+		w.wg.Add(1)
+		if w.fsw != nil {
+			go w.notifyLoop()
+		} else {
+			// changes made as soon as Watch returns are seen
+			go w.pollLoop(w.stat())
+		}
+	}
+	for _, p := range providers {
+		w.wg.Add(1)
+		go func(p WatchableProvider) {
+			defer w.wg.Done()
+			p.Watch(w.done, w.reload, w.error)
+		}(p)
+	}
+	return w, nil
+}
+
+// newNotifyWatcher watches dirs and the directories holding files, so that files replaced
+// by a rename are still seen. It returns nil if notifications can't be used.
+func newNotifyWatcher(files map[string]bool, dirs map[string]bool) *fsnotify.Watcher {
+	fsw, e := fsnotify.NewWatcher()
+	if e != nil {
+		return nil
+	}
+	watched := make(map[string]bool)
+	for dir := range dirs {
+		watched[dir] = true
+	}
+	for path := range files {
+		watched[filepath.Dir(path)] = true
+	}
+	for dir := range watched {
+		if e := fsw.Add(dir); e != nil {
+			fsw.Close()
+			return nil
+		}
+	}
+	return fsw
+}
+
+// Close stops watching. It waits for a reload in progress to finish.
+func (w *Watcher) Close() error {
+	close(w.done)
+	var e error
+	if w.fsw != nil {
+		e = w.fsw.Close()
+	}
+	w.wg.Wait()
+	return e
+}
+
+func (w *Watcher) reload() {
+	if _, e := w.c.Reload(); e != nil {
+		w.error(e)
+	}
+}
+
+func (w *Watcher) error(e error) {
+	if w.opts.OnError != nil {
+		w.opts.OnError(e)
+	}
+}
+
+func (w *Watcher) notifyLoop() {
+	defer w.wg.Done()
+
+	var timer *time.Timer
+	var fire <-chan time.Time
+	for {
+		select {
+		case ev, ok := <-w.fsw.Events:
+			if !ok {
+				return
+			}
+			if name := filepath.Clean(ev.Name); !w.files[name] && !w.dirs[filepath.Dir(name)] {
+				continue
+			}
+			if timer == nil {
+				timer = time.NewTimer(w.opts.Delay)
+				fire = timer.C
+			} else {
+				timer.Reset(w.opts.Delay)
+			}
+		case e, ok := <-w.fsw.Errors:
+			if !ok {
+				return
+			}
+			w.error(e)
+		case <-fire:
+			timer, fire = nil, nil
+			w.reload()
+		case <-w.done:
+			if timer != nil {
+				timer.Stop()
+			}
+			return
+		}
+	}
+}
+
+// fileState is what polling compares to detect a change.
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+func (w *Watcher) pollLoop(states map[string]fileState) {
+	defer w.wg.Done()
+
+	ticker := time.NewTicker(w.opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			current := w.stat()
+			if !reflect.DeepEqual(states, current) {
+				states = current
+				w.reload()
+			}
+		case <-w.done:
+			return
+		}
+	}
+}
+
+func (w *Watcher) stat() map[string]fileState {
+	states := make(map[string]fileState, len(w.files))
+	for path := range w.files {
+		if fi, e := os.Stat(path); e == nil {
+			states[path] = fileState{fi.ModTime(), fi.Size()}
+		}
+	}
+	// every file in the directories, so that new and removed files are seen too
+	for dir := range w.dirs {
+		entries, _ := ioutil.ReadDir(dir)
+		for _, fi := range entries {
+			states[filepath.Join(dir, fi.Name())] = fileState{fi.ModTime(), fi.Size()}
+		}
+	}
+	return states
+}
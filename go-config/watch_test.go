@@ -0,0 +1,138 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func writeTestFile(t *testing.T, path string, content string) {
+	if e := ioutil.WriteFile(path, []byte(content), 0644); e != nil {
+		t.Fatalf("Error writing %s: '%s'", path, e)
+	}
+}
+
+func TestReload(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "config")
+	defer os.RemoveAll(dir)
+	base := filepath.Join(dir, "base.json")
+	local := filepath.Join(dir, "local.json")
+	writeTestFile(t, base, `{"log": {"level": "info", "file": "app.log"}, "port": 80}`)
+	writeTestFile(t, local, `{"log": {"level": "warn"}}`)
+
+	conf := NewConfig()
+	conf.AddFile(base, "", false)
+	conf.AddFile(local, "", true)
+	conf.AddDefaultOverride("port", "8080")
+
+	var notified []string
+	conf.OnChange(func(changed []string) {
+		notified = changed
+	})
+
+	writeTestFile(t, base, `{"log": {"level": "debug", "format": "json"}, "port": 81}`)
+	writeTestFile(t, local, `{"log": {"level": "error"}}`)
+	changed, e := conf.Reload()
+	if e != nil {
+		t.Fatalf("Error reloading: '%s'", e)
+	}
+
+	// the override order is kept: local.json still beats base.json, and the default
+	// added with override still beats both.
+	if conf.GetString("log.level") != "error" {
+		t.Errorf("Expected log.level to be \"error\" after reload, got '%v'", conf.Get("log.level"))
+	}
+	if conf.GetString("port") != "8080" {
+		t.Errorf("Expected port to stay \"8080\" after reload, got '%v'", conf.Get("port"))
+	}
+	expected := []string{"log.file", "log.format", "log.level"}
+	if !reflect.DeepEqual(changed, expected) || !reflect.DeepEqual(notified, expected) {
+		t.Errorf("Expected changed keys %v, got %v and notified %v", expected, changed, notified)
+	}
+
+	// a broken file leaves the config alone
+	writeTestFile(t, local, `{"log": `)
+	if _, e = conf.Reload(); e == nil {
+		t.Errorf("Expected an error reloading a broken file, didn't get one")
+	}
+	if conf.GetString("log.level") != "error" {
+		t.Errorf("Expected log.level to still be \"error\" after a failed reload, got '%v'", conf.Get("log.level"))
+	}
+	if _, e = conf.LastReload(); e == nil {
+		t.Errorf("Expected LastReload to report the failed reload")
+	}
+}
+
+func TestWatch(t *testing.T) {
+	for _, poll := range []bool{false, true} {
+		dir, _ := ioutil.TempDir("", "config")
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "app.json")
+		writeTestFile(t, path, `{"log": {"level": "info"}}`)
+
+		conf, _ := ReadFromFile(path)
+		notified := make(chan []string, 1)
+		conf.OnChange(func(changed []string) {
+			notified <- changed
+		})
+
+		w, e := conf.Watch(WatchOptions{Poll: poll, Interval: 10 * time.Millisecond, Delay: 10 * time.Millisecond})
+		if e != nil {
+			t.Fatalf("Error watching: '%s'", e)
+		}
+
+		// make sure the modification time moves on for polling
+		time.Sleep(20 * time.Millisecond)
+		writeTestFile(t, path, `{"log": {"level": "debug"}}`)
+
+		select {
+		case changed := <-notified:
+			if !reflect.DeepEqual(changed, []string{"log.level"}) {
+				t.Errorf("Expected log.level to change, got %v", changed)
+			}
+			if conf.GetString("log.level") != "debug" {
+				t.Errorf("Expected log.level to be reloaded as \"debug\", got '%v'", conf.Get("log.level"))
+			}
+		case <-time.After(5 * time.Second):
+			t.Errorf("Timed out waiting for a reload (poll=%v)", poll)
+		}
+		w.Close()
+	}
+
+	// drop-ins added to a directory are picked up
+	for _, poll := range []bool{false, true} {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{"10-base.json": `{"log": {"level": "info"}}`})
+
+		conf := NewConfig()
+		if e := conf.AddDirectory(dir, "", ""); e != nil {
+			t.Fatal(e)
+		}
+		notified := make(chan []string, 1)
+		conf.OnChange(func(changed []string) {
+			notified <- changed
+		})
+		w, e := conf.Watch(WatchOptions{Poll: poll, Interval: 10 * time.Millisecond, Delay: 10 * time.Millisecond})
+		if e != nil {
+			t.Fatalf("Error watching: '%s'", e)
+		}
+
+		writeFiles(t, dir, map[string]string{"20-debug.json": `{"log": {"level": "debug"}}`})
+		select {
+		case <-notified:
+			if conf.GetString("log.level") != "debug" {
+				t.Errorf("Expected log.level from the new file, got '%v'", conf.Get("log.level"))
+			}
+		case <-time.After(5 * time.Second):
+			t.Errorf("Timed out waiting for a new file to be read (poll=%v)", poll)
+		}
+		w.Close()
+	}
+
+	if _, e := NewConfig().Watch(WatchOptions{}); e == nil {
+		t.Errorf("Expected an error watching a config without files, didn't get one")
+	}
+}
@@ -0,0 +1,43 @@
+package configdebug
+
+import (
+	"bytes"
+
+	"github.com/kataras/iris"
+	"github.com/roporter/go-libs/go-config"
+)
+
+// ConfigDebug serves the redacted configuration of a running instance, the source of each
+// key, and the result of the last reload. It reveals a lot about a deployment, so mount it
+// behind the ipfilter middleware, e.g.
+//
+//	filter := ipfilter.New(ipfilter.Options{AllowedIPs: []string{"10.0.0.0/8"}, BlockByDefault: true})
+//	iris.Get("/debug/config", filter.Serve, configdebug.New(conf))
+type ConfigDebug struct {
+	conf config.Config
+}
+
+// Serve writes the config as JSON, or as an HTML table if asked for with ?format=html or
+// by a browser.
+func (d *ConfigDebug) Serve(ctx *iris.Context) {
+	info := d.conf.DebugInfo()
+	ctx.Response.Header.Set("Cache-Control", "no-store")
+
+	if config.WantsDebugHTML(ctx.URLParam("format"), ctx.RequestHeader("Accept")) {
+		var buf bytes.Buffer
+		if err := config.WriteDebugHTML(&buf, info); err != nil {
+			ctx.SetStatusCode(iris.StatusInternalServerError)
+			ctx.Write("500 Internal Server Error")
+			return
+		}
+		ctx.SetContentType("text/html; charset=UTF-8")
+		ctx.Write("%s", buf.String())
+		return
+	}
+	ctx.Render("application/json", info, iris.RenderOptions{"charset": "UTF-8"})
+}
+
+func New(conf config.Config) iris.HandlerFunc {
+	d := &ConfigDebug{conf: conf}
+	return d.Serve
+}